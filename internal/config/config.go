@@ -3,8 +3,11 @@ package config
 import (
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,33 +20,352 @@ const (
 	TestTypeLoad      TestType = "load"
 	TestTypeSpike     TestType = "spike"
 	TestTypeEndurance TestType = "endurance"
+	TestTypeReplay    TestType = "replay"
+	TestTypeMatrix    TestType = "matrix"  // Load test repeated for every combination of the matrix middleware
+	TestTypeCompare   TestType = "compare" // Load test repeated against the Helix and net/http servers
 )
 
+// Embedded server implementations.
+const (
+	ServerImplHelix   = "helix"   // Helix server
+	ServerImplNetHTTP = "nethttp" // Plain net/http server with the same routes, as a baseline
+)
+
+// Reseed modes select when the runner restores the dataset through the admin routes.
+const (
+	ReseedNone  = "none"  // Never reseed; only take snapshots
+	ReseedRun   = "run"   // Reseed before the run
+	ReseedPhase = "phase" // Reseed before the run and at every phase boundary
+)
+
+// StoreBackends lists the item store backends of the embedded server.
+var StoreBackends = []string{"mutex", "sharded", "syncmap", "file"}
+
+// MiddlewareNames lists the middleware of the embedded server in the order it is installed.
+var MiddlewareNames = []string{"requestid", "logger", "recover", "compress"}
+
+// ParseMiddleware parses a comma-separated list of middleware names; "none" selects no middleware.
+func ParseMiddleware(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "none" {
+		return []string{}, nil
+	}
+	names := parseList(s)
+	for _, name := range names {
+		if !slices.Contains(MiddlewareNames, name) {
+			return nil, fmt.Errorf("invalid middleware: %s (must be %s)", name, strings.Join(MiddlewareNames, ", "))
+		}
+	}
+	return names, nil
+}
+
+// Executor represents how requests are scheduled.
+type Executor string
+
+const (
+	ExecutorRate Executor = "rate" // Workers share a global request rate
+	ExecutorVU   Executor = "vu"   // Each virtual user runs its own loop with think time and pacing
+)
+
+// Protocol represents the HTTP protocol used by the runner.
+type Protocol string
+
+const (
+	ProtocolHTTP1 Protocol = "http1" // HTTP/1.1 over plain TCP or TLS
+	ProtocolH2    Protocol = "h2"    // HTTP/2 over TLS
+	ProtocolH2C   Protocol = "h2c"   // HTTP/2 over plain TCP (prior knowledge)
+)
+
+// Mode represents the role of the process in a test.
+type Mode string
+
+const (
+	ModeStandalone  Mode = "standalone"  // Run the server and the load generator in one process
+	ModeCoordinator Mode = "coordinator" // Run the server and distribute the load to agents
+	ModeAgent       Mode = "agent"       // Generate load on behalf of a coordinator
+)
+
+// SlowMode represents the behaviour of an adversarial slow client.
+type SlowMode string
+
+const (
+	SlowUpload  SlowMode = "upload"  // Sends the request body one byte per interval
+	SlowRead    SlowMode = "read"    // Reads the response one byte per interval
+	SlowIdle    SlowMode = "idle"    // Opens a connection and never sends a request
+	SlowHeaders SlowMode = "headers" // Sends the request headers one byte per interval and never finishes them
+)
+
+// AllSlowModes lists every slow client mode.
+var AllSlowModes = []SlowMode{SlowUpload, SlowRead, SlowIdle, SlowHeaders}
+
+// ParseSlowModes parses a comma-separated list of slow client modes.
+func ParseSlowModes(s string) ([]SlowMode, error) {
+	var modes []SlowMode
+	for _, part := range parseList(s) {
+		switch mode := SlowMode(part); mode {
+		case SlowUpload, SlowRead, SlowIdle, SlowHeaders:
+			modes = append(modes, mode)
+		default:
+			return nil, fmt.Errorf("invalid slow client mode: %s (must be upload, read, idle, or headers)", part)
+		}
+	}
+	return modes, nil
+}
+
+// ThinkTime describes the distribution of pauses a virtual user takes between requests.
+type ThinkTime struct {
+	Kind string        // constant, uniform, or exponential (empty for no think time)
+	Min  time.Duration // Constant value, lower bound for uniform, or mean for exponential
+	Max  time.Duration // Upper bound for uniform
+}
+
+// ParseThinkTime parses a think time specification such as "constant:500ms",
+// "uniform:100ms-1s" or "exponential:300ms". An empty string means no think time.
+func ParseThinkTime(s string) (ThinkTime, error) {
+	if s == "" || s == "none" {
+		return ThinkTime{}, nil
+	}
+
+	kind, arg, ok := strings.Cut(s, ":")
+	if !ok {
+		return ThinkTime{}, fmt.Errorf("invalid think time %q (expected KIND:VALUE)", s)
+	}
+
+	switch kind {
+	case "constant", "exponential":
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			return ThinkTime{}, fmt.Errorf("invalid think time duration %q", arg)
+		}
+		return ThinkTime{Kind: kind, Min: d}, nil
+	case "uniform":
+		lo, hi, ok := strings.Cut(arg, "-")
+		if !ok {
+			return ThinkTime{}, fmt.Errorf("invalid uniform think time %q (expected MIN-MAX)", arg)
+		}
+		min, err := time.ParseDuration(lo)
+		if err != nil || min < 0 {
+			return ThinkTime{}, fmt.Errorf("invalid think time duration %q", lo)
+		}
+		max, err := time.ParseDuration(hi)
+		if err != nil || max < min {
+			return ThinkTime{}, fmt.Errorf("invalid think time duration %q", hi)
+		}
+		return ThinkTime{Kind: kind, Min: min, Max: max}, nil
+	default:
+		return ThinkTime{}, fmt.Errorf("invalid think time kind: %s (must be constant, uniform, or exponential)", kind)
+	}
+}
+
+// String returns the think time in the format accepted by ParseThinkTime.
+func (t ThinkTime) String() string {
+	switch t.Kind {
+	case "":
+		return "none"
+	case "uniform":
+		return fmt.Sprintf("uniform:%s-%s", t.Min, t.Max)
+	default:
+		return fmt.Sprintf("%s:%s", t.Kind, t.Min)
+	}
+}
+
+// ArrivalKind represents the distribution of request arrivals for the rate executor.
+type ArrivalKind string
+
+const (
+	ArrivalConstant ArrivalKind = "constant" // Perfectly periodic arrivals
+	ArrivalPoisson  ArrivalKind = "poisson"  // Exponentially distributed inter-arrival times
+	ArrivalUniform  ArrivalKind = "uniform"  // Periodic arrivals with uniform jitter
+	ArrivalBursty   ArrivalKind = "bursty"   // On/off bursts with the same average rate
+)
+
+// Arrival describes how request arrivals are distributed in time.
+type Arrival struct {
+	Kind     ArrivalKind
+	Jitter   float64       // Maximum deviation from the mean interval as a fraction (uniform)
+	BurstOn  time.Duration // Length of each burst (bursty)
+	BurstOff time.Duration // Pause between bursts (bursty)
+}
+
+// String returns a human-readable description of the distribution.
+func (a Arrival) String() string {
+	switch a.Kind {
+	case ArrivalUniform:
+		return fmt.Sprintf("uniform (±%.0f%% jitter)", a.Jitter*100)
+	case ArrivalBursty:
+		return fmt.Sprintf("bursty (%s on, %s off)", a.BurstOn, a.BurstOff)
+	default:
+		return string(a.Kind)
+	}
+}
+
+// SpikeShape represents how a spike's request rate rises and falls.
+type SpikeShape string
+
+const (
+	SpikeSquare      SpikeShape = "square"      // Peak rate for the whole spike
+	SpikeTriangle    SpikeShape = "triangle"    // Linear rise to the peak at the midpoint, then linear fall
+	SpikeExponential SpikeShape = "exponential" // Exponential rise to the peak at the midpoint, then exponential decay
+)
+
+// Spike describes a single spike in a spike schedule.
+type Spike struct {
+	Offset   time.Duration // Start of the spike relative to the start of the test
+	PeakRPS  int           // Additional request rate at the peak of the spike
+	Shape    SpikeShape
+	Duration time.Duration
+}
+
+// ParseSpikeSchedule parses a comma-separated spike schedule where each spike is
+// OFFSET:PEAK_RPS[:SHAPE[:DURATION]], e.g. "10s:1000:square:5s,40s:2000:triangle:10s".
+// The shape defaults to square and the duration to defaultDuration.
+func ParseSpikeSchedule(s string, defaultDuration time.Duration) ([]Spike, error) {
+	var spikes []Spike
+	for _, part := range parseList(s) {
+		fields := strings.Split(part, ":")
+		if len(fields) < 2 || len(fields) > 4 {
+			return nil, fmt.Errorf("invalid spike %q (expected OFFSET:PEAK_RPS[:SHAPE[:DURATION]])", part)
+		}
+
+		offset, err := time.ParseDuration(fields[0])
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid spike offset %q", fields[0])
+		}
+		if offset < time.Second {
+			return nil, fmt.Errorf("invalid spike offset %q (must be at least 1s, so the baseline before the spike can be measured)", fields[0])
+		}
+		peak, err := strconv.Atoi(fields[1])
+		if err != nil || peak <= 0 {
+			return nil, fmt.Errorf("invalid spike peak RPS %q", fields[1])
+		}
+
+		spike := Spike{Offset: offset, PeakRPS: peak, Shape: SpikeSquare, Duration: defaultDuration}
+		if len(fields) > 2 {
+			spike.Shape = SpikeShape(fields[2])
+			switch spike.Shape {
+			case SpikeSquare, SpikeTriangle, SpikeExponential:
+				// Valid
+			default:
+				return nil, fmt.Errorf("invalid spike shape: %s (must be square, triangle, or exponential)", fields[2])
+			}
+		}
+		if len(fields) > 3 {
+			if spike.Duration, err = time.ParseDuration(fields[3]); err != nil || spike.Duration <= 0 {
+				return nil, fmt.Errorf("invalid spike duration %q", fields[3])
+			}
+		}
+		spikes = append(spikes, spike)
+	}
+	return spikes, nil
+}
+
+// Spikes returns the spike schedule. Without an explicit schedule a square spike of
+// SpikeRPS lasting SpikeDuration starts every 2*SpikeDuration.
+func (c *Config) Spikes() []Spike {
+	if len(c.SpikeSchedule) > 0 {
+		return c.SpikeSchedule
+	}
+
+	var spikes []Spike
+	for offset := 2 * c.SpikeDuration; offset < c.Duration; offset += 2 * c.SpikeDuration {
+		spikes = append(spikes, Spike{
+			Offset:   offset,
+			PeakRPS:  c.SpikeRPS,
+			Shape:    SpikeSquare,
+			Duration: c.SpikeDuration,
+		})
+	}
+	return spikes
+}
+
 // Config holds all configuration for the stress test.
 type Config struct {
 	// Server configuration
-	ServerAddr string
+	ServerAddr   string
+	TargetURL    string        // Base URL of an external server (scheme, host and optional path prefix)
+	NoServer     bool          // Don't start the embedded server
+	HealthPath   string        // Path probed until the server is ready (empty to disable)
+	ReadyTimeout time.Duration // Maximum time to wait for the server to become ready
+
+	// Embedded server implementation and middleware
+	ServerImpl       string   // Embedded server implementation
+	Middleware       []string // Middleware installed on the embedded Helix server
+	MatrixMiddleware []string // Middleware toggled on and off by the matrix test type
+	MatrixRepeats    int      // Runs of every variant of the matrix and compare test types
+	FaultFile        string   // JSON file configuring faults injected by the embedded Helix server
+	Store            string   // Item store backend of the embedded server
+	StoreFile        string   // Log file of the file store backend (empty for a temporary file)
+	AdminToken       string   // Token of the server's admin routes (generated for the embedded server)
+	Reseed           string   // When the runner reseeds the dataset through the admin routes
+	Verify           bool     // Check the final dataset against the acknowledged writes
+	Linearizability  bool     // Record item histories and check them against a register model
+
+	// TLS and protocol configuration
+	TLS      bool     // Serve the embedded server over TLS with a self-signed certificate
+	Protocol Protocol // HTTP protocol used by the runner
+	TLSCA    string   // PEM file with additional certificates to trust
+	TLSRoots []byte   // PEM certificates to trust in memory (the embedded server's generated certificate)
+	Insecure bool     // Skip server certificate verification
 
 	// Test configuration
 	TestType      TestType
 	Duration      time.Duration
+	Warmup        time.Duration // Traffic sent before Duration starts, excluded from the results
 	TargetRPS     int
 	Concurrent    int
 	SpikeDuration time.Duration
 	SpikeRPS      int
+	SpikeSchedule []Spike // Explicit spike schedule (empty for periodic SpikeRPS spikes)
+
+	// Executor configuration
+	Executor  Executor
+	VUs       int           // Number of virtual users (vu executor, 0 uses Concurrent)
+	ThinkTime ThinkTime     // Pause between requests of a virtual user
+	Pacing    time.Duration // Minimum duration of a virtual user iteration (0 to disable)
+	Arrival   Arrival       // Arrival distribution of the rate executor
+	Seed      int64         // Random seed for arrivals and generated values (0 for time-based)
 
 	// Request configuration
 	Timeout time.Duration
 
+	// Connection configuration
+	KeepAlive       bool // Reuse connections between requests
+	ConnChurn       int  // Close the connection after every Nth request (0 to disable)
+	MaxConnsPerHost int  // Maximum connections per host, including active ones (0 for unlimited)
+
+	// Slow client configuration
+	SlowClients  int           // Number of adversarial slow connections held alongside the load (0 to disable)
+	SlowModes    []SlowMode    // Slow client behaviours, assigned to the clients in turn
+	SlowInterval time.Duration // Interval between the bytes sent or read by slow clients
+	SlowStart    time.Duration // Time after the start of the test when the slow clients connect
+
 	// Report configuration
 	ReportFormat string
 	ReportFile   string
+	Breakdown    bool // Report requests per endpoint (always on in replay tests)
 
 	// Endpoints to test
-	Endpoints []string
+	Endpoints     []string
+	ScenarioFile  string // Scenario file replacing Endpoints
+	OpenAPIFile   string // OpenAPI document to generate endpoints from
+	WriteScenario string // Write the generated scenario to this file and exit
+
+	// HAR import configuration
+	HARFile         string   // HAR file to generate flows from
+	HARHosts        []string // Hosts to import (empty for all)
+	HARContentTypes []string // Response content type prefixes to import (empty for all)
 
 	// Dataset configuration
 	DatasetSize int // Number of items to pre-populate (0 for empty store)
+
+	// Replay configuration
+	ReplayFile  string  // JSON-lines request log to replay
+	ReplaySpeed float64 // Timing multiplier (1 = original timing, 0 = as fast as possible)
+
+	// Distributed configuration
+	Mode        Mode
+	Agents      []string // Agent addresses for the coordinator (host:port, tcp://host:port, or unix:///path)
+	AgentListen string   // Address the agent listens on
+	AgentToken  string   // Shared secret the coordinator proves to the agents
 }
 
 // Default returns a Config with default values.
@@ -56,9 +378,26 @@ func Default() *Config {
 		Concurrent:    10,
 		SpikeDuration: 5 * time.Second,
 		SpikeRPS:      1000,
-		Timeout:       30 * time.Second,
-		ReportFormat:  "text",
-		ReportFile:    "",
+		Executor:      ExecutorRate,
+		Arrival: Arrival{
+			Kind:     ArrivalConstant,
+			Jitter:   0.5,
+			BurstOn:  time.Second,
+			BurstOff: time.Second,
+		},
+		Timeout:          30 * time.Second,
+		KeepAlive:        true,
+		SlowModes:        AllSlowModes,
+		ServerImpl:       ServerImplHelix,
+		Store:            "mutex",
+		Reseed:           ReseedRun,
+		Middleware:       MiddlewareNames,
+		MatrixMiddleware: MiddlewareNames,
+		MatrixRepeats:    1,
+		SlowInterval:     time.Second,
+		SlowStart:        10 * time.Second,
+		ReportFormat:     "text",
+		ReportFile:       "",
 		Endpoints: []string{
 			"GET:/",
 			"GET:/ping",
@@ -69,7 +408,13 @@ func Default() *Config {
 			"PUT:/items/{id}",           // Dynamic ID from dataset range
 			"DELETE:/items/{delete_id}", // Dynamic ID from high range to avoid conflicts
 		},
-		DatasetSize: 10000, // Pre-populate with 10,000 items by default
+		DatasetSize:  10000, // Pre-populate with 10,000 items by default
+		ReplaySpeed:  1,
+		Protocol:     ProtocolHTTP1,
+		HealthPath:   "/health",
+		ReadyTimeout: 10 * time.Second,
+		Mode:         ModeStandalone,
+		AgentListen:  "tcp://127.0.0.1:7070",
 	}
 }
 
@@ -78,20 +423,86 @@ func Parse() (*Config, error) {
 	cfg := Default()
 
 	// Command-line flags
-	flag.StringVar(&cfg.ServerAddr, "server-addr", getEnv("SERVER_ADDR", cfg.ServerAddr), "Server address to test")
-	flag.StringVar((*string)(&cfg.TestType), "type", getEnv("TEST_TYPE", string(cfg.TestType)), "Test type: load, spike, or endurance")
+	flag.StringVar(&cfg.ServerAddr, "server-addr", getEnv("SERVER_ADDR", cfg.ServerAddr), "Server address to test (host:port or unix:///path.sock)")
+	flag.StringVar(&cfg.TargetURL, "target-url", getEnv("TARGET_URL", cfg.TargetURL), "Base URL of an external server to test (e.g., https://api.example.com/v1); implies --no-server")
+	flag.BoolVar(&cfg.NoServer, "no-server", parseBoolEnv("NO_SERVER", cfg.NoServer), "Don't start the embedded server; test the server at --server-addr")
+	flag.BoolVar(&cfg.TLS, "tls", parseBoolEnv("TLS", cfg.TLS), "Serve the embedded server over TLS with a generated self-signed certificate")
+	flag.StringVar((*string)(&cfg.Protocol), "protocol", getEnv("PROTOCOL", string(cfg.Protocol)), "HTTP protocol: http1, h2 (HTTP/2 over TLS), or h2c (HTTP/2 without TLS)")
+	flag.StringVar(&cfg.TLSCA, "tls-ca", getEnv("TLS_CA", cfg.TLSCA), "PEM file with certificates to trust in addition to the system roots")
+	flag.BoolVar(&cfg.Insecure, "insecure", parseBoolEnv("INSECURE", cfg.Insecure), "Skip server certificate verification")
+	flag.StringVar(&cfg.HealthPath, "health-path", getEnv("HEALTH_PATH", cfg.HealthPath), "Path probed until the server is ready (empty to disable)")
+	flag.DurationVar(&cfg.ReadyTimeout, "ready-timeout", parseDurationEnv("READY_TIMEOUT", cfg.ReadyTimeout), "Maximum time to wait for the server to become ready")
+	flag.StringVar((*string)(&cfg.TestType), "type", getEnv("TEST_TYPE", string(cfg.TestType)), "Test type: load, spike, endurance, replay, matrix, or compare")
+	flag.StringVar(&cfg.ServerImpl, "server-impl", getEnv("SERVER_IMPL", cfg.ServerImpl), "Embedded server implementation: helix, or nethttp (plain net/http baseline)")
+
+	var middlewareFlag, matrixMiddlewareFlag string
+	flag.StringVar(&middlewareFlag, "middleware", getEnv("MIDDLEWARE", strings.Join(MiddlewareNames, ",")), "Comma-separated middleware installed on the embedded server (requestid, logger, recover, compress, or none)")
+	flag.StringVar(&matrixMiddlewareFlag, "matrix-middleware", getEnv("MATRIX_MIDDLEWARE", strings.Join(MiddlewareNames, ",")), "Comma-separated middleware toggled on and off by the matrix test type")
+	flag.IntVar(&cfg.MatrixRepeats, "matrix-repeats", parseIntEnv("MATRIX_REPEATS", cfg.MatrixRepeats), "Runs of every variant of the matrix and compare test types, in a random order each round")
+	flag.StringVar(&cfg.FaultFile, "faults", getEnv("FAULTS_FILE", cfg.FaultFile), "JSON file configuring faults injected by the embedded server (latency, errors, panics, resets, partial writes)")
+	flag.StringVar(&cfg.Store, "store", getEnv("STORE", cfg.Store), "Item store backend of the embedded server: mutex, sharded, syncmap, or file")
+	flag.StringVar(&cfg.StoreFile, "store-file", getEnv("STORE_FILE", cfg.StoreFile), "Log file of the file store (default: a temporary file)")
+	flag.StringVar(&cfg.AdminToken, "admin-token", getEnv("ADMIN_TOKEN", cfg.AdminToken), "Token of the server's admin routes (default: generated for the embedded server; external servers without one get no admin calls)")
+	flag.StringVar(&cfg.Reseed, "reseed", getEnv("RESEED", cfg.Reseed), "When to reseed the dataset through the admin routes: none, run (before the run), or phase (also at phase boundaries)")
+	flag.BoolVar(&cfg.Verify, "verify", parseBoolEnv("VERIFY", cfg.Verify), "Log acknowledged item writes and check them against the final dataset after the run")
+	flag.BoolVar(&cfg.Linearizability, "linearizability", parseBoolEnv("LINEARIZABILITY", cfg.Linearizability), "Record the history of every item and check it is linearizable after the run")
 	flag.DurationVar(&cfg.Duration, "duration", parseDurationEnv("DURATION", cfg.Duration), "Test duration")
+	flag.DurationVar(&cfg.Warmup, "warmup", parseDurationEnv("WARMUP", cfg.Warmup), "Warm-up period before the test whose metrics are excluded from the results")
 	flag.IntVar(&cfg.TargetRPS, "rps", parseIntEnv("TARGET_RPS", cfg.TargetRPS), "Target requests per second")
-	flag.IntVar(&cfg.Concurrent, "concurrent", parseIntEnv("CONCURRENT", cfg.Concurrent), "Number of concurrent connections")
+	flag.IntVar(&cfg.Concurrent, "concurrent", parseIntEnv("CONCURRENT", cfg.Concurrent), "Number of concurrent connections, and requests in flight at most with the rate executor")
 	flag.DurationVar(&cfg.SpikeDuration, "spike-duration", parseDurationEnv("SPIKE_DURATION", cfg.SpikeDuration), "Spike test duration")
 	flag.IntVar(&cfg.SpikeRPS, "spike-rps", parseIntEnv("SPIKE_RPS", cfg.SpikeRPS), "Spike test RPS")
+
+	var spikeScheduleFlag string
+	flag.StringVar(&spikeScheduleFlag, "spike-schedule", getEnv("SPIKE_SCHEDULE", ""), "Spike schedule: comma-separated OFFSET:PEAK_RPS[:SHAPE[:DURATION]] (shapes: square, triangle, exponential)")
+	flag.StringVar((*string)(&cfg.Executor), "executor", getEnv("EXECUTOR", string(cfg.Executor)), "Executor: rate (shared request rate) or vu (virtual users with think time)")
+	flag.IntVar(&cfg.VUs, "vus", parseIntEnv("VUS", cfg.VUs), "Number of virtual users for the vu executor (0 uses --concurrent)")
+	flag.DurationVar(&cfg.Pacing, "pacing", parseDurationEnv("PACING", cfg.Pacing), "Minimum duration of each virtual user iteration (0 to disable)")
+
+	flag.StringVar((*string)(&cfg.Arrival.Kind), "arrival", getEnv("ARRIVAL", string(cfg.Arrival.Kind)), "Arrival distribution: constant, poisson, uniform, or bursty")
+	flag.Float64Var(&cfg.Arrival.Jitter, "arrival-jitter", parseFloatEnv("ARRIVAL_JITTER", cfg.Arrival.Jitter), "Maximum interval jitter as a fraction of the mean interval (uniform arrivals)")
+	flag.DurationVar(&cfg.Arrival.BurstOn, "burst-on", parseDurationEnv("BURST_ON", cfg.Arrival.BurstOn), "Burst length (bursty arrivals)")
+	flag.DurationVar(&cfg.Arrival.BurstOff, "burst-off", parseDurationEnv("BURST_OFF", cfg.Arrival.BurstOff), "Pause between bursts (bursty arrivals)")
+	flag.Int64Var(&cfg.Seed, "seed", parseInt64Env("SEED", cfg.Seed), "Random seed for arrivals and generated values (0 for time-based)")
+
+	var thinkTimeFlag string
+	flag.StringVar(&thinkTimeFlag, "think-time", getEnv("THINK_TIME", ""), "Virtual user think time: constant:DUR, uniform:MIN-MAX, or exponential:MEAN")
+
 	flag.DurationVar(&cfg.Timeout, "timeout", parseDurationEnv("TIMEOUT", cfg.Timeout), "Request timeout")
+	flag.BoolVar(&cfg.KeepAlive, "keep-alive", parseBoolEnv("KEEP_ALIVE", cfg.KeepAlive), "Reuse connections between requests (false opens a connection per request)")
+	flag.IntVar(&cfg.ConnChurn, "conn-churn", parseIntEnv("CONN_CHURN", cfg.ConnChurn), "Close the connection after every Nth request (0 to disable)")
+	flag.IntVar(&cfg.MaxConnsPerHost, "max-conns", parseIntEnv("MAX_CONNS_PER_HOST", cfg.MaxConnsPerHost), "Maximum connections per host, including active ones (0 for unlimited)")
+	flag.IntVar(&cfg.SlowClients, "slow-clients", parseIntEnv("SLOW_CLIENTS", cfg.SlowClients), "Number of slow client connections held alongside the load (0 to disable)")
+	flag.DurationVar(&cfg.SlowInterval, "slow-interval", parseDurationEnv("SLOW_INTERVAL", cfg.SlowInterval), "Interval between the bytes sent or read by slow clients")
+	flag.DurationVar(&cfg.SlowStart, "slow-start", parseDurationEnv("SLOW_START", cfg.SlowStart), "Time after the start of the test when slow clients connect (the baseline before it is compared against)")
+
+	var slowModesFlag string
+	flag.StringVar(&slowModesFlag, "slow-modes", getEnv("SLOW_MODES", "upload,read,idle,headers"), "Comma-separated slow client modes: upload, read, idle, headers")
 	flag.StringVar(&cfg.ReportFormat, "format", getEnv("REPORT_FORMAT", cfg.ReportFormat), "Report format: text, json")
 	flag.StringVar(&cfg.ReportFile, "output", getEnv("REPORT_FILE", cfg.ReportFile), "Output file for report (default: results/{type}-test.{format}, empty for stdout)")
+	flag.BoolVar(&cfg.Breakdown, "breakdown", parseBoolEnv("BREAKDOWN", cfg.Breakdown), "Report requests, errors and latency per endpoint (always on in replay tests)")
 	flag.IntVar(&cfg.DatasetSize, "dataset-size", parseIntEnv("DATASET_SIZE", cfg.DatasetSize), "Number of items to pre-populate (0 for empty store)")
 
+	flag.StringVar(&cfg.ReplayFile, "replay-file", getEnv("REPLAY_FILE", cfg.ReplayFile), "JSON-lines request log to replay (replay test type)")
+	flag.Float64Var(&cfg.ReplaySpeed, "replay-speed", parseFloatEnv("REPLAY_SPEED", cfg.ReplaySpeed), "Replay timing multiplier (1 = original timing, 2 = twice as fast, 0 = as fast as possible)")
+
 	var endpointsFlag string
 	flag.StringVar(&endpointsFlag, "endpoints", getEnv("ENDPOINTS", ""), "Comma-separated list of endpoints (e.g., GET:/,POST:/items)")
+	flag.StringVar(&cfg.ScenarioFile, "scenario", getEnv("SCENARIO_FILE", cfg.ScenarioFile), "Scenario file with the endpoints to test (overrides --endpoints)")
+	flag.StringVar(&cfg.OpenAPIFile, "openapi", getEnv("OPENAPI_FILE", cfg.OpenAPIFile), "OpenAPI 3 document (JSON or YAML) to generate endpoints from")
+	flag.StringVar(&cfg.WriteScenario, "write-scenario", getEnv("WRITE_SCENARIO", cfg.WriteScenario), "Write the scenario generated from --openapi or --har to this file and exit")
+	flag.StringVar(&cfg.HARFile, "har", getEnv("HAR_FILE", cfg.HARFile), "HAR file to generate request flows from")
+
+	var harHostsFlag, harContentTypesFlag string
+	flag.StringVar(&harHostsFlag, "har-hosts", getEnv("HAR_HOSTS", ""), "Comma-separated hosts to import from the HAR file (default: all)")
+	flag.StringVar(&harContentTypesFlag, "har-content-types", getEnv("HAR_CONTENT_TYPES", ""), "Comma-separated response content types to import from the HAR file (e.g., application/json)")
+
+	flag.StringVar((*string)(&cfg.Mode), "mode", getEnv("MODE", string(cfg.Mode)), "Process mode: standalone, coordinator, or agent")
+	flag.StringVar(&cfg.AgentListen, "listen", getEnv("AGENT_LISTEN", cfg.AgentListen), "Address the agent listens on (tcp://host:port or unix:///path)")
+
+	var agentsFlag string
+	flag.StringVar(&agentsFlag, "agents", getEnv("AGENTS", ""), "Comma-separated agent addresses for the coordinator (tcp://host:port or unix:///path)")
+	flag.StringVar(&cfg.AgentToken, "agent-token", getEnv("AGENT_TOKEN", ""), "Shared secret authenticating the coordinator to the agents (required for TCP agents)")
 
 	flag.Parse()
 
@@ -102,6 +513,36 @@ func Parse() (*Config, error) {
 		cfg.Endpoints = parseEndpoints(envEndpoints)
 	}
 
+	// Resolve a time-based seed so the report can state the seed that was used
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	thinkTime, err := ParseThinkTime(thinkTimeFlag)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ThinkTime = thinkTime
+
+	if cfg.SpikeSchedule, err = ParseSpikeSchedule(spikeScheduleFlag, cfg.SpikeDuration); err != nil {
+		return nil, err
+	}
+
+	if cfg.Middleware, err = ParseMiddleware(middlewareFlag); err != nil {
+		return nil, err
+	}
+	if cfg.MatrixMiddleware, err = ParseMiddleware(matrixMiddlewareFlag); err != nil {
+		return nil, err
+	}
+
+	if cfg.SlowModes, err = ParseSlowModes(slowModesFlag); err != nil {
+		return nil, err
+	}
+
+	cfg.HARHosts = parseList(harHostsFlag)
+	cfg.Agents = parseList(agentsFlag)
+	cfg.HARContentTypes = parseList(harContentTypesFlag)
+
 	// Set default output file if not specified
 	if cfg.ReportFile == "" {
 		// Ensure results directory exists
@@ -126,23 +567,186 @@ func Parse() (*Config, error) {
 	return cfg, nil
 }
 
+// VirtualUsers returns the number of virtual users for the vu executor.
+func (c *Config) VirtualUsers() int {
+	if c.VUs > 0 {
+		return c.VUs
+	}
+	return c.Concurrent
+}
+
+// EmbeddedServer reports whether the embedded test server should be started.
+func (c *Config) EmbeddedServer() bool {
+	return !c.NoServer && c.TargetURL == "" && c.Mode != ModeAgent
+}
+
+// UnixSocket returns the socket path if the server address is a Unix domain socket
+// (unix:///path.sock), or an empty string otherwise.
+func (c *Config) UnixSocket() string {
+	path, ok := strings.CutPrefix(c.ServerAddr, "unix://")
+	if !ok || c.TargetURL != "" {
+		return ""
+	}
+	return path
+}
+
+// BaseURL returns the URL request paths are appended to: the target URL without a
+// trailing slash, or the server address over HTTP or HTTPS. Requests to a Unix
+// domain socket use localhost as the host.
+func (c *Config) BaseURL() string {
+	if c.TargetURL != "" {
+		return strings.TrimSuffix(c.TargetURL, "/")
+	}
+
+	// Handle both ":8080" and "localhost:8080" formats
+	addr := c.ServerAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	if c.UnixSocket() != "" {
+		addr = "localhost"
+	}
+	if c.TLS {
+		return "https://" + addr
+	}
+	return "http://" + addr
+}
+
 // Validate validates the configuration.
 func (c *Config) Validate() error {
-	if c.ServerAddr == "" {
+	if c.ServerAddr == "" && c.TargetURL == "" {
 		return fmt.Errorf("server address cannot be empty")
 	}
 
+	if c.TargetURL == "" && c.ServerAddr == "unix://" {
+		return fmt.Errorf("unix socket address must include a path (e.g., unix:///tmp/helix.sock)")
+	}
+
+	if c.TargetURL != "" {
+		u, err := url.Parse(c.TargetURL)
+		if err != nil {
+			return fmt.Errorf("invalid target URL: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid target URL: %s (expected http:// or https:// with a host)", c.TargetURL)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("target URL cannot have a query or fragment")
+		}
+	}
+
+	if c.TLS && c.TargetURL != "" {
+		return fmt.Errorf("--tls applies to the embedded server; use an https target URL instead")
+	}
+
+	secure := strings.HasPrefix(c.BaseURL(), "https://")
+	switch c.Protocol {
+	case ProtocolHTTP1:
+		// Valid
+	case ProtocolH2:
+		if !secure {
+			return fmt.Errorf("protocol h2 requires TLS (use --tls, an https target URL, or h2c)")
+		}
+	case ProtocolH2C:
+		if secure {
+			return fmt.Errorf("protocol h2c cannot be used with TLS (use h2)")
+		}
+	default:
+		return fmt.Errorf("invalid protocol: %s (must be http1, h2, or h2c)", c.Protocol)
+	}
+
+	if c.HealthPath != "" && !strings.HasPrefix(c.HealthPath, "/") {
+		return fmt.Errorf("health path must start with /")
+	}
+
+	if c.ReadyTimeout <= 0 {
+		return fmt.Errorf("ready timeout must be positive")
+	}
+
 	switch c.TestType {
 	case TestTypeLoad, TestTypeSpike, TestTypeEndurance:
 		// Valid
+	case TestTypeReplay:
+		if c.ReplayFile == "" {
+			return fmt.Errorf("replay test requires a replay file")
+		}
+	case TestTypeMatrix:
+		if !c.EmbeddedServer() || c.Mode != ModeStandalone {
+			return fmt.Errorf("matrix tests require the embedded server in standalone mode")
+		}
+		if len(c.MatrixMiddleware) == 0 {
+			return fmt.Errorf("matrix tests require at least one matrix middleware")
+		}
+		if c.ServerImpl != ServerImplHelix {
+			return fmt.Errorf("matrix tests require the helix server implementation")
+		}
+		if c.MatrixRepeats < 1 {
+			return fmt.Errorf("matrix repeats must be at least 1")
+		}
+	case TestTypeCompare:
+		if !c.EmbeddedServer() || c.Mode != ModeStandalone {
+			return fmt.Errorf("compare tests require the embedded server in standalone mode")
+		}
+		if c.MatrixRepeats < 1 {
+			return fmt.Errorf("matrix repeats must be at least 1")
+		}
+	default:
+		return fmt.Errorf("invalid test type: %s (must be load, spike, endurance, replay, matrix, or compare)", c.TestType)
+	}
+
+	switch c.ServerImpl {
+	case ServerImplHelix, ServerImplNetHTTP:
+		// Valid
 	default:
-		return fmt.Errorf("invalid test type: %s (must be load, spike, or endurance)", c.TestType)
+		return fmt.Errorf("invalid server implementation: %s (must be helix or nethttp)", c.ServerImpl)
+	}
+
+	switch c.Reseed {
+	case ReseedNone, ReseedRun, ReseedPhase:
+		// Valid
+	default:
+		return fmt.Errorf("invalid reseed mode: %s (must be none, run, or phase)", c.Reseed)
+	}
+
+	if c.Verify {
+		if c.Mode != ModeStandalone || c.TestType == TestTypeMatrix || c.TestType == TestTypeCompare {
+			return fmt.Errorf("verification requires a standalone load, spike, endurance, or replay test")
+		}
+		if !c.EmbeddedServer() && c.AdminToken == "" {
+			return fmt.Errorf("verification requires the admin token of the external server")
+		}
+	}
+
+	if c.Linearizability {
+		if c.Mode != ModeStandalone || c.TestType == TestTypeMatrix || c.TestType == TestTypeCompare {
+			return fmt.Errorf("linearizability checks require a standalone load, spike, endurance, or replay test")
+		}
+		if c.Reseed == ReseedPhase {
+			return fmt.Errorf("linearizability checks cannot be combined with --reseed=phase")
+		}
+	}
+
+	if !slices.Contains(StoreBackends, c.Store) {
+		return fmt.Errorf("invalid store: %s (must be %s)", c.Store, strings.Join(StoreBackends, ", "))
+	}
+
+	if c.FaultFile != "" {
+		if !c.EmbeddedServer() || c.ServerImpl != ServerImplHelix {
+			return fmt.Errorf("fault injection requires the embedded helix server")
+		}
+		if c.TestType == TestTypeMatrix || c.TestType == TestTypeCompare {
+			return fmt.Errorf("fault injection cannot be combined with %s tests", c.TestType)
+		}
 	}
 
 	if c.Duration <= 0 {
 		return fmt.Errorf("duration must be positive")
 	}
 
+	if c.Warmup < 0 {
+		return fmt.Errorf("warm-up cannot be negative")
+	}
+
 	if c.TargetRPS <= 0 {
 		return fmt.Errorf("target RPS must be positive")
 	}
@@ -151,10 +755,78 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("concurrent connections must be positive")
 	}
 
+	switch c.Executor {
+	case ExecutorRate, ExecutorVU:
+		// Valid
+	default:
+		return fmt.Errorf("invalid executor: %s (must be rate or vu)", c.Executor)
+	}
+
+	if c.VUs < 0 {
+		return fmt.Errorf("virtual users cannot be negative")
+	}
+
+	if c.Pacing < 0 {
+		return fmt.Errorf("pacing cannot be negative")
+	}
+
+	switch c.Arrival.Kind {
+	case ArrivalConstant, ArrivalPoisson:
+		// Valid
+	case ArrivalUniform:
+		if c.Arrival.Jitter < 0 || c.Arrival.Jitter > 1 {
+			return fmt.Errorf("arrival jitter must be between 0 and 1")
+		}
+	case ArrivalBursty:
+		if c.Arrival.BurstOn <= 0 || c.Arrival.BurstOff < 0 {
+			return fmt.Errorf("burst on duration must be positive and off duration cannot be negative")
+		}
+	default:
+		return fmt.Errorf("invalid arrival distribution: %s (must be constant, poisson, uniform, or bursty)", c.Arrival.Kind)
+	}
+
+	if c.ReplaySpeed < 0 {
+		return fmt.Errorf("replay speed cannot be negative")
+	}
+
+	if c.TestType == TestTypeSpike {
+		if len(c.SpikeSchedule) == 0 && (c.SpikeDuration <= 0 || c.SpikeRPS <= 0) {
+			return fmt.Errorf("spike duration and spike RPS must be positive")
+		}
+		for i, spike := range c.SpikeSchedule {
+			if spike.Offset >= c.Duration {
+				return fmt.Errorf("spike %d starts after the end of the test", i+1)
+			}
+		}
+	}
+
 	if c.Timeout <= 0 {
 		return fmt.Errorf("timeout must be positive")
 	}
 
+	if c.ConnChurn < 0 {
+		return fmt.Errorf("connection churn cannot be negative")
+	}
+
+	if c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("max connections per host cannot be negative")
+	}
+
+	if c.SlowClients < 0 {
+		return fmt.Errorf("slow clients cannot be negative")
+	}
+	if c.SlowClients > 0 {
+		if len(c.SlowModes) == 0 {
+			return fmt.Errorf("slow clients require at least one slow client mode")
+		}
+		if c.SlowInterval <= 0 {
+			return fmt.Errorf("slow client interval must be positive")
+		}
+		if c.SlowStart < 0 || c.SlowStart >= c.Duration {
+			return fmt.Errorf("slow client start must be between 0 and the test duration")
+		}
+	}
+
 	switch c.ReportFormat {
 	case "text", "json":
 		// Valid
@@ -162,13 +834,84 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid report format: %s (must be text or json)", c.ReportFormat)
 	}
 
-	if len(c.Endpoints) == 0 {
+	sources := 0
+	for _, source := range []string{c.ScenarioFile, c.OpenAPIFile, c.HARFile} {
+		if source != "" {
+			sources++
+		}
+	}
+
+	if len(c.Endpoints) == 0 && sources == 0 {
 		return fmt.Errorf("at least one endpoint must be specified")
 	}
 
+	if sources > 1 {
+		return fmt.Errorf("only one of scenario file, OpenAPI document, or HAR file can be used")
+	}
+
+	if c.WriteScenario != "" && c.OpenAPIFile == "" && c.HARFile == "" {
+		return fmt.Errorf("writing a scenario requires an OpenAPI document or HAR file")
+	}
+
+	switch c.Mode {
+	case ModeStandalone:
+		// Valid
+	case ModeCoordinator:
+		if len(c.Agents) == 0 {
+			return fmt.Errorf("coordinator mode requires at least one agent")
+		}
+		if c.TestType == TestTypeReplay {
+			return fmt.Errorf("replay tests cannot be distributed")
+		}
+		if c.Reseed == ReseedPhase {
+			return fmt.Errorf("--reseed=phase cannot be distributed; the coordinator reseeds only before the run")
+		}
+		if c.Executor == ExecutorRate && c.TargetRPS < len(c.Agents) {
+			return fmt.Errorf("target RPS must be at least the number of agents")
+		}
+		remote := false
+		for _, agent := range c.Agents {
+			if !strings.HasPrefix(agent, "unix://") {
+				if c.AgentToken == "" {
+					return fmt.Errorf("agent %s: TCP agents require an agent token (--agent-token)", agent)
+				}
+				if host, _, err := net.SplitHostPort(strings.TrimPrefix(agent, "tcp://")); err != nil || !isLocalHost(host) {
+					remote = true
+				}
+			}
+		}
+		if remote {
+			if c.UnixSocket() != "" {
+				return fmt.Errorf("remote agents can't reach a Unix socket server; use a routable --server-addr or --target-url")
+			}
+			if u, err := url.Parse(c.BaseURL()); err != nil || isLocalHost(u.Hostname()) {
+				return fmt.Errorf("remote agents can't reach %s; use a routable --server-addr (e.g., 10.0.0.5:8080) or --target-url", c.BaseURL())
+			}
+		}
+	case ModeAgent:
+		if c.AgentListen == "" {
+			return fmt.Errorf("agent mode requires a listen address")
+		}
+		if !strings.HasPrefix(c.AgentListen, "unix://") && c.AgentToken == "" {
+			return fmt.Errorf("agents listening on TCP require an agent token (--agent-token)")
+		}
+	default:
+		return fmt.Errorf("invalid mode: %s (must be standalone, coordinator, or agent)", c.Mode)
+	}
+
 	return nil
 }
 
+// isLocalHost reports whether host only reaches the local machine: empty,
+// localhost, or a loopback or unspecified IP address.
+func isLocalHost(host string) bool {
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
 // getEnv gets an environment variable or returns the default value.
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -177,6 +920,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// parseBoolEnv parses a boolean environment variable or returns the default value.
+func parseBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // parseIntEnv parses an integer environment variable or returns the default value.
 func parseIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -187,6 +940,26 @@ func parseIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// parseInt64Env parses a 64-bit integer environment variable or returns the default value.
+func parseInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// parseFloatEnv parses a float environment variable or returns the default value.
+func parseFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // parseDurationEnv parses a duration environment variable or returns the default value.
 func parseDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
@@ -199,6 +972,11 @@ func parseDurationEnv(key string, defaultValue time.Duration) time.Duration {
 
 // parseEndpoints parses a comma-separated list of endpoints.
 func parseEndpoints(s string) []string {
+	return parseList(s)
+}
+
+// parseList parses a comma-separated list, dropping empty entries.
+func parseList(s string) []string {
 	if s == "" {
 		return nil
 	}
@@ -0,0 +1,155 @@
+package distributed
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+	"github.com/kolosys/helix-stress-test/internal/netutil"
+	"github.com/kolosys/helix-stress-test/internal/runner"
+)
+
+// authTimeout bounds the time a coordinator has to answer the challenge. The
+// coordinator connects to its agents one by one before answering any of them.
+const authTimeout = 30 * time.Second
+
+// Serve runs an agent listening on the given address until the context is canceled.
+// The agent runs one test at a time; a coordinator that connects while a test is
+// running waits until it has finished. Unless token is empty, only coordinators
+// that sign the agent's challenge with the same token can run tests.
+func Serve(ctx context.Context, address, token string) error {
+	// A stale socket left by a previous agent is removed
+	ln, err := netutil.Listen(address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	fmt.Printf("Agent listening on %s\n", address)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handle(ctx, conn, token, &mu)
+		}()
+	}
+}
+
+// handle authenticates a coordinator, runs the test it requested and sends back
+// the result. Tests hold mu, which is only taken once the coordinator has
+// authenticated, so a peer that never answers the challenge can't block others.
+func handle(ctx context.Context, conn net.Conn, token string, mu *sync.Mutex) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		fmt.Fprintf(os.Stderr, "Agent: failed to generate challenge: %v\n", err)
+		return
+	}
+	challenge := hex.EncodeToString(nonce)
+	if err := enc.Encode(Message{Type: MessageChallenge, Nonce: challenge}); err != nil {
+		fmt.Fprintf(os.Stderr, "Agent: failed to send challenge to %s: %v\n", conn.RemoteAddr(), err)
+		return
+	}
+
+	var msg Message
+	if err := dec.Decode(&msg); err != nil {
+		fmt.Fprintf(os.Stderr, "Agent: failed to read message from %s: %v\n", conn.RemoteAddr(), err)
+		return
+	}
+	if token != "" && !hmac.Equal([]byte(msg.Auth), []byte(sign(token, challenge))) {
+		fmt.Fprintf(os.Stderr, "Agent: rejected unauthenticated coordinator %s\n", conn.RemoteAddr())
+		enc.Encode(Message{Type: MessageError, Error: "invalid agent token"})
+		return
+	}
+
+	conn.SetReadDeadline(time.Time{})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	reply := Message{Type: MessageResult}
+	state, err := run(ctx, dec, msg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Agent: %v\n", err)
+		reply = Message{Type: MessageError, Error: err.Error()}
+	} else {
+		reply.State = state
+	}
+
+	if err := enc.Encode(reply); err != nil {
+		fmt.Fprintf(os.Stderr, "Agent: failed to send result: %v\n", err)
+	}
+}
+
+// run waits for the start time and runs the test. The test stops early if the
+// coordinator sends a stop message or disconnects.
+func run(ctx context.Context, dec *json.Decoder, msg Message) (*metrics.State, error) {
+	if msg.Type != MessageRun || msg.Config == nil {
+		return nil, fmt.Errorf("unexpected message: %s", msg.Type)
+	}
+	cfg := msg.Config
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		var stop Message
+		dec.Decode(&stop)
+		cancel()
+	}()
+
+	timer := time.NewTimer(time.Until(msg.StartAt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("test canceled before start")
+	case <-timer.C:
+	}
+
+	fmt.Printf("[%s] Running %s test (duration: %s, RPS: %d, concurrent: %d)...\n",
+		time.Now().Format("2006-01-02 15:04:05"), cfg.TestType, cfg.Duration, cfg.TargetRPS, cfg.Concurrent)
+
+	m := metrics.New()
+	r, err := runner.New(cfg, m)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Scenario != nil {
+		r.UseScenario(msg.Scenario)
+	}
+	if err := r.Run(ctx); err != nil {
+		return nil, fmt.Errorf("test failed: %w", err)
+	}
+
+	state := m.Export()
+	fmt.Printf("[%s] Test finished: %d requests\n", time.Now().Format("2006-01-02 15:04:05"), state.TotalRequests)
+	return &state, nil
+}
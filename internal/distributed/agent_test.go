@@ -0,0 +1,80 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dialAgent connects to the agent at path, retrying until it listens, and reads
+// its challenge.
+func dialAgent(t *testing.T, path string) (net.Conn, *json.Decoder, string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("failed to connect to agent: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		dec := json.NewDecoder(conn)
+		var challenge Message
+		if err := dec.Decode(&challenge); err != nil {
+			t.Fatalf("failed to read challenge: %v", err)
+		}
+		if challenge.Type != MessageChallenge {
+			t.Fatalf("first message = %s, want %s", challenge.Type, MessageChallenge)
+		}
+		return conn, dec, challenge.Nonce
+	}
+}
+
+func TestServeSilentPeer(t *testing.T) {
+	const token = "secret"
+	path := filepath.Join(t.TempDir(), "agent.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, "unix://"+path, token) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// A peer that takes the challenge and never answers it
+	dialAgent(t, path)
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		// A run message without a config fails once the agent runs it
+		{name: "signed", token: token, want: "unexpected message: run"},
+		{name: "wrong token", token: "other", want: "invalid agent token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, dec, nonce := dialAgent(t, path)
+			if err := json.NewEncoder(conn).Encode(Message{Type: MessageRun, Auth: sign(tt.token, nonce)}); err != nil {
+				t.Fatalf("failed to send run message: %v", err)
+			}
+			var reply Message
+			if err := dec.Decode(&reply); err != nil {
+				t.Fatalf("failed to read reply: %v", err)
+			}
+			if reply.Type != MessageError || reply.Error != tt.want {
+				t.Errorf("reply = %s %q, want %s %q", reply.Type, reply.Error, MessageError, tt.want)
+			}
+		})
+	}
+}
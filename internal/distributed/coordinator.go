@@ -0,0 +1,171 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+	"github.com/kolosys/helix-stress-test/internal/scenario"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	startDelay  = 2 * time.Second // Time for every agent to receive its share before the synchronized start
+)
+
+// Coordinate distributes the test to the configured agents, starts them in sync,
+// and merges their metrics into m. The scenario, if any, is sent to the agents so
+// they do not need access to the scenario source. Canceling the context stops the
+// agents early; their partial results are still merged.
+func Coordinate(ctx context.Context, cfg *config.Config, s *scenario.Scenario, m *metrics.Metrics) error {
+	// Agents may not have the CA file, so its certificates are sent with the test
+	roots := cfg.TLSRoots
+	if cfg.TLSCA != "" {
+		pem, err := os.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		roots = append(slices.Clip(roots), pem...)
+	}
+
+	n := len(cfg.Agents)
+	conns := make([]net.Conn, 0, n)
+	decs := make([]*json.Decoder, 0, n)
+	challenges := make([]string, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	for _, agent := range cfg.Agents {
+		network, addr := ParseAddress(agent)
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to agent %s: %w", agent, err)
+		}
+		conns = append(conns, conn)
+
+		dec := json.NewDecoder(conn)
+		var challenge Message
+		conn.SetReadDeadline(time.Now().Add(dialTimeout))
+		if err := dec.Decode(&challenge); err != nil {
+			return fmt.Errorf("failed to read challenge from agent %s: %w", agent, err)
+		}
+		conn.SetReadDeadline(time.Time{})
+		if challenge.Type != MessageChallenge {
+			return fmt.Errorf("agent %s: unexpected message: %s", agent, challenge.Type)
+		}
+		decs = append(decs, dec)
+		challenges = append(challenges, challenge.Nonce)
+	}
+
+	startAt := time.Now().Add(startDelay)
+	for i, conn := range conns {
+		msg := Message{
+			Type:     MessageRun,
+			Auth:     sign(cfg.AgentToken, challenges[i]),
+			Config:   agentConfig(cfg, roots, s != nil, i, n),
+			Scenario: s,
+			StartAt:  startAt,
+		}
+		if err := json.NewEncoder(conn).Encode(msg); err != nil {
+			return fmt.Errorf("failed to send test to agent %s: %w", cfg.Agents[i], err)
+		}
+	}
+
+	// Stop the agents if the coordinator is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			for _, conn := range conns {
+				json.NewEncoder(conn).Encode(Message{Type: MessageStop})
+			}
+		case <-done:
+		}
+	}()
+
+	results := make([]Message, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn net.Conn) {
+			defer wg.Done()
+			if err := decs[i].Decode(&results[i]); err != nil {
+				errs[i] = fmt.Errorf("failed to read result from agent %s: %w", cfg.Agents[i], err)
+			}
+		}(i, conn)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			continue
+		}
+		switch {
+		case result.Type == MessageError:
+			errs[i] = fmt.Errorf("agent %s: %s", cfg.Agents[i], result.Error)
+		case result.Type != MessageResult || result.State == nil:
+			errs[i] = fmt.Errorf("agent %s: unexpected message: %s", cfg.Agents[i], result.Type)
+		default:
+			m.Merge(*result.State)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// agentConfig returns the share of the test run by agent i of n. Rates and
+// connection counts are split evenly across the agents, and each agent gets its
+// own seed so they do not generate identical traffic. Agents trust the PEM
+// certificates in roots. The coordinator manages the dataset, so agents don't
+// get the admin token.
+func agentConfig(cfg *config.Config, roots []byte, hasScenario bool, i, n int) *config.Config {
+	c := *cfg
+	c.Mode = config.ModeAgent
+	c.Agents = nil
+	c.AgentToken = ""
+	c.AdminToken = ""
+	c.TLSCA = ""
+	c.TLSRoots = roots
+	c.Seed = cfg.Seed + int64(i)
+	c.TargetRPS = max(1, share(cfg.TargetRPS, i, n))
+	c.Concurrent = max(1, share(cfg.Concurrent, i, n))
+	if cfg.VUs > 0 {
+		c.VUs = max(1, share(cfg.VUs, i, n))
+	}
+	c.SpikeRPS = max(1, share(cfg.SpikeRPS, i, n))
+	c.SlowClients = share(cfg.SlowClients, i, n)
+	c.SpikeSchedule = make([]config.Spike, len(cfg.SpikeSchedule))
+	for j, spike := range cfg.SpikeSchedule {
+		spike.PeakRPS = max(1, share(spike.PeakRPS, i, n))
+		c.SpikeSchedule[j] = spike
+	}
+
+	if hasScenario {
+		// The scenario is sent with the test
+		c.ScenarioFile, c.OpenAPIFile, c.HARFile = "", "", ""
+	}
+	return &c
+}
+
+// share returns the part of total assigned to agent i of n; the remainder goes to
+// the first agents.
+func share(total, i, n int) int {
+	s := total / n
+	if i < total%n {
+		s++
+	}
+	return s
+}
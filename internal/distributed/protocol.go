@@ -0,0 +1,59 @@
+package distributed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+	"github.com/kolosys/helix-stress-test/internal/scenario"
+)
+
+// MessageType identifies a message exchanged between the coordinator and an agent.
+type MessageType string
+
+const (
+	MessageChallenge MessageType = "challenge" // Agent to coordinator: nonce to sign with the agent token
+	MessageRun       MessageType = "run"       // Coordinator to agent: run a test
+	MessageStop      MessageType = "stop"      // Coordinator to agent: stop the running test early
+	MessageResult    MessageType = "result"    // Agent to coordinator: metrics of the finished test
+	MessageError     MessageType = "error"     // Agent to coordinator: the test could not be run
+)
+
+// Message is a single message of the protocol. Messages are sent as JSON values
+// over a TCP or Unix socket connection; each connection runs one test. The agent
+// opens every connection with a challenge, and the run message must carry its
+// nonce signed with the shared agent token.
+type Message struct {
+	Type     MessageType
+	Nonce    string             `json:",omitempty"` // Random challenge (challenge)
+	Auth     string             `json:",omitempty"` // Nonce signed with the agent token (run)
+	Config   *config.Config     `json:",omitempty"` // Agent's share of the test (run)
+	Scenario *scenario.Scenario `json:",omitempty"` // Workload replacing the configured endpoints (run)
+	StartAt  time.Time          `json:",omitempty"` // Time at which all agents start (run)
+	State    *metrics.State     `json:",omitempty"` // Collected metrics (result)
+	Error    string             `json:",omitempty"` // Failure reason (error)
+}
+
+// sign returns the HMAC-SHA256 of nonce keyed with token, so the token itself never
+// goes over the connection.
+func sign(token, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseAddress splits an agent address into a network and an address for net.Dial.
+// Addresses are tcp://host:port, unix:///path/to/socket, or a plain host:port.
+func ParseAddress(s string) (network, address string) {
+	if path, ok := strings.CutPrefix(s, "unix://"); ok {
+		return "unix", path
+	}
+	if addr, ok := strings.CutPrefix(s, "tcp://"); ok {
+		return "tcp", addr
+	}
+	return "tcp", s
+}
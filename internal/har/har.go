@@ -0,0 +1,271 @@
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/scenario"
+)
+
+// File is the subset of an HTTP Archive (HAR 1.2) file needed to build flows.
+type File struct {
+	Log Log `json:"log"`
+}
+
+// Log is the root object of a HAR file.
+type Log struct {
+	Pages   []Page  `json:"pages"`
+	Entries []Entry `json:"entries"`
+}
+
+// Page groups the entries recorded for one page load.
+type Page struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Entry is a single recorded request and response.
+type Entry struct {
+	PageRef         string    `json:"pageref"`
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"` // Total elapsed time in milliseconds
+	Request         Request   `json:"request"`
+	Response        Response  `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []NameValue `json:"headers"`
+	PostData *PostData   `json:"postData"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status  int     `json:"status"`
+	Content Content `json:"content"`
+}
+
+// Content describes the response body.
+type Content struct {
+	MimeType string `json:"mimeType"`
+}
+
+// PostData is a recorded request body.
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Text     string      `json:"text"`
+	Params   []NameValue `json:"params"`
+}
+
+// NameValue is a header or form parameter.
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Options controls which entries are imported.
+type Options struct {
+	Hosts        []string // Hosts to keep (host or host:port); empty keeps all
+	ContentTypes []string // Response content type prefixes to keep; empty keeps all
+}
+
+// skippedHeaders are not replayed because the client sets them or they are connection-specific.
+var skippedHeaders = map[string]bool{
+	"host":                true,
+	"content-length":      true,
+	"connection":          true,
+	"keep-alive":          true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"te":                  true,
+	"trailer":             true,
+	"proxy-connection":    true,
+	"proxy-authorization": true,
+}
+
+// Load reads a HAR file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file: %w", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Import converts the entries into flows, one per page (or a single flow if the
+// file has no pages). Entries are ordered by start time and each step's think time
+// is the gap between the end of the previous entry and the start of this one.
+func (f *File) Import(source string, opts Options) (*scenario.Scenario, error) {
+	pageTitles := make(map[string]string, len(f.Log.Pages))
+	for _, page := range f.Log.Pages {
+		pageTitles[page.ID] = page.Title
+	}
+
+	var order []string
+	groups := make(map[string][]Entry)
+	for _, entry := range f.Log.Entries {
+		if !opts.keep(entry) {
+			continue
+		}
+		if _, ok := groups[entry.PageRef]; !ok {
+			order = append(order, entry.PageRef)
+		}
+		groups[entry.PageRef] = append(groups[entry.PageRef], entry)
+	}
+
+	s := &scenario.Scenario{Source: source}
+	for _, ref := range order {
+		entries := groups[ref]
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+		})
+
+		name := pageTitles[ref]
+		if name == "" {
+			name = ref
+		}
+		flow := scenario.Flow{Name: name}
+
+		var prevEnd time.Time
+		for _, entry := range entries {
+			step, err := entryStep(entry)
+			if err != nil {
+				return nil, err
+			}
+			if !prevEnd.IsZero() {
+				if gap := entry.StartedDateTime.Sub(prevEnd); gap > 0 {
+					step.ThinkTimeMS = gap.Milliseconds()
+				}
+			}
+			end := entry.StartedDateTime.Add(time.Duration(entry.Time * float64(time.Millisecond)))
+			if end.After(prevEnd) {
+				prevEnd = end
+			}
+			flow.Steps = append(flow.Steps, step)
+		}
+		s.Flows = append(s.Flows, flow)
+	}
+
+	if len(s.Flows) == 0 {
+		return nil, fmt.Errorf("no HAR entries match the host and content type filters")
+	}
+	return s, nil
+}
+
+// keep reports whether the entry passes the host and content type filters.
+func (o Options) keep(entry Entry) bool {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return false
+	}
+
+	if len(o.Hosts) > 0 {
+		matched := false
+		for _, host := range o.Hosts {
+			if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(o.ContentTypes) > 0 {
+		mimeType := strings.ToLower(entry.Response.Content.MimeType)
+		matched := false
+		for _, ct := range o.ContentTypes {
+			if strings.HasPrefix(mimeType, strings.ToLower(ct)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// entryStep converts a HAR entry into a flow step, keeping the path, query, headers and body.
+func entryStep(entry Entry) (scenario.Step, error) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil {
+		return scenario.Step{}, fmt.Errorf("invalid HAR request URL %q: %w", entry.Request.URL, err)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	method := strings.ToUpper(entry.Request.Method)
+	step := scenario.Step{
+		Endpoint: scenario.Endpoint{
+			Name:   method + " " + u.Path,
+			Method: method,
+			Path:   path,
+		},
+	}
+
+	for _, h := range entry.Request.Headers {
+		name := strings.ToLower(h.Name)
+		if strings.HasPrefix(name, ":") || skippedHeaders[name] {
+			continue
+		}
+		if step.Headers == nil {
+			step.Headers = make(map[string]string)
+		}
+		step.Headers[h.Name] = h.Value
+	}
+
+	if pd := entry.Request.PostData; pd != nil {
+		switch {
+		case pd.Text != "":
+			step.Body = pd.Text
+		case len(pd.Params) > 0:
+			form := url.Values{}
+			for _, p := range pd.Params {
+				form.Add(p.Name, p.Value)
+			}
+			step.Body = form.Encode()
+		}
+		if step.Body != "" && pd.MimeType != "" {
+			if step.Headers == nil {
+				step.Headers = make(map[string]string)
+			}
+			if !hasHeader(step.Headers, "Content-Type") {
+				step.Headers["Content-Type"] = pd.MimeType
+			}
+		}
+	}
+
+	return step, nil
+}
+
+// hasHeader reports whether headers contains name, ignoring case.
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+// Package linearizability checks histories of operations on an item against a
+// register model: the item holds a value or is absent, and every operation must
+// appear to take effect atomically at some point between its call and its return.
+//
+// The search follows Wing and Gong's algorithm with Lowe's memoization of the
+// states already explored, as used by tools like Knossos and Porcupine.
+package linearizability
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of an operation on an item.
+type Kind string
+
+// Operation kinds.
+const (
+	Create Kind = "create"
+	Read   Kind = "read"
+	Update Kind = "update"
+	Delete Kind = "delete"
+)
+
+// Operation is an operation on an item, as observed by a client. Call and Return
+// are relative to a common origin.
+type Operation struct {
+	Kind    Kind
+	Call    time.Duration
+	Return  time.Duration
+	Pending bool   // No response was received: it may or may not have taken effect, at any time after its call
+	Input   string // Value sent by creates and updates
+	Found   bool   // The item existed (reads, updates and deletes)
+	Output  string // Value returned by creates, and by reads and updates that found the item
+}
+
+// String describes the operation, e.g. `[1.2ms, 1.5ms] update "a" -> "a"`.
+func (op Operation) String() string {
+	var b strings.Builder
+	call, ret := op.Call.Round(time.Microsecond), op.Return.Round(time.Microsecond)
+	if op.Pending {
+		fmt.Fprintf(&b, "[%s, ?] %s", call, op.Kind)
+	} else {
+		fmt.Fprintf(&b, "[%s, %s] %s", call, ret, op.Kind)
+	}
+	if op.Kind == Create || op.Kind == Update {
+		fmt.Fprintf(&b, " %q", op.Input)
+	}
+
+	switch {
+	case op.Pending:
+		b.WriteString(" -> no response")
+	case op.Kind == Create, op.Found && op.Kind != Delete:
+		fmt.Fprintf(&b, " -> %q", op.Output)
+	case op.Found:
+		b.WriteString(" -> ok")
+	default:
+		b.WriteString(" -> not found")
+	}
+	return b.String()
+}
+
+// Result is the outcome of a check.
+type Result int
+
+const (
+	Linearizable    Result = iota
+	NotLinearizable        // No order of the operations satisfies the register model
+	Unknown                // The search was abandoned at its step limit
+)
+
+func (r Result) String() string {
+	switch r {
+	case Linearizable:
+		return "linearizable"
+	case NotLinearizable:
+		return "not linearizable"
+	default:
+		return "unknown"
+	}
+}
+
+// state is the state of the register.
+type state struct {
+	known   bool // False until an operation reveals the initial state
+	present bool
+	value   string
+}
+
+// step applies op to s, reporting whether its output is possible in state s.
+// Pending operations are always possible.
+func step(s state, op Operation) (state, bool) {
+	absent := state{known: true}
+	holding := func(value string) state {
+		return state{known: true, present: true, value: value}
+	}
+
+	if op.Pending {
+		switch op.Kind {
+		case Create:
+			if s.known && !s.present {
+				return holding(op.Input), true
+			}
+		case Update:
+			if s.known && s.present {
+				return holding(op.Input), true
+			}
+		case Delete:
+			return absent, true
+		}
+		return s, true
+	}
+
+	// The output must match the state, unless it is still unknown
+	switch op.Kind {
+	case Create:
+		if s.known && s.present || op.Output != op.Input {
+			return s, false
+		}
+		return holding(op.Output), true
+	case Read:
+		if s.known && (s.present != op.Found || s.present && s.value != op.Output) {
+			return s, false
+		}
+		if !op.Found {
+			return absent, true
+		}
+		return holding(op.Output), true
+	case Update, Delete:
+		if s.known && s.present != op.Found {
+			return s, false
+		}
+		switch {
+		case !op.Found || op.Kind == Delete:
+			return absent, true
+		case op.Output != op.Input:
+			return s, false
+		default:
+			return holding(op.Input), true
+		}
+	}
+	return s, false
+}
+
+// entry is the call or return of an operation in the history.
+type entry struct {
+	op         int
+	call       bool
+	time       time.Duration
+	match      *entry // Return of a call
+	prev, next *entry
+}
+
+// history links the call and return entries of ops in time order, after a
+// sentinel head. Calls sort before returns at the same time, so the operations
+// are treated as concurrent. Pending reads constrain nothing and are left out.
+func history(ops []Operation) *entry {
+	entries := make([]*entry, 0, 2*len(ops))
+	for i, op := range ops {
+		if op.Pending && op.Kind == Read {
+			continue
+		}
+		ret := &entry{op: i, time: op.Return}
+		if op.Pending {
+			ret.time = math.MaxInt64
+		}
+		entries = append(entries, &entry{op: i, call: true, time: op.Call, match: ret}, ret)
+	}
+	slices.SortStableFunc(entries, func(a, b *entry) int {
+		if c := cmp.Compare(a.time, b.time); c != 0 {
+			return c
+		}
+		switch {
+		case a.call == b.call:
+			return 0
+		case a.call:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	head := &entry{}
+	prev := head
+	for _, e := range entries {
+		prev.next, e.prev = e, prev
+		prev = e
+	}
+	return head
+}
+
+// lift removes a call and its return from the history.
+func lift(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+// unlift restores a call and its return removed by lift.
+func unlift(e *entry) {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+// Check reports whether the operations on an item are linearizable. The item is
+// initially absent if the history contains its create, otherwise its initial
+// state is unknown. With a positive limit, the search is abandoned after that
+// many steps.
+func Check(ops []Operation, limit int) Result {
+	return check(ops, slices.ContainsFunc(ops, func(op Operation) bool { return op.Kind == Create }), limit)
+}
+
+// check reports whether ops are linearizable from an absent item if known, or
+// from an unknown state.
+func check(ops []Operation, known bool, limit int) Result {
+	s := state{known: known}
+
+	type frame struct {
+		e *entry
+		s state
+	}
+	var stack []frame
+	linearized := make([]byte, (len(ops)+7)/8)
+	seen := make(map[string]struct{})
+
+	head := history(ops)
+	e := head.next
+	for steps := 0; head.next != nil; steps++ {
+		if limit > 0 && steps >= limit {
+			return Unknown
+		}
+
+		if e.call {
+			if next, ok := step(s, ops[e.op]); ok {
+				linearized[e.op/8] |= 1 << (e.op % 8)
+				key := fmt.Sprintf("%s|%t|%t|%s", linearized, next.known, next.present, next.value)
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					stack = append(stack, frame{e, s})
+					s = next
+					lift(e)
+					e = head.next
+					continue
+				}
+				linearized[e.op/8] &^= 1 << (e.op % 8)
+			}
+			e = e.next
+			continue
+		}
+
+		// An operation returned before it could be linearized: backtrack
+		if len(stack) == 0 {
+			return NotLinearizable
+		}
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		s = f.s
+		linearized[f.e.op/8] &^= 1 << (f.e.op % 8)
+		unlift(f.e)
+		e = f.e.next
+	}
+	return Linearizable
+}
+
+// Minimize returns the operations of a non-linearizable history that make it so.
+// The history is first cut down to the shortest time window that still isn't
+// linearizable. Operations in the window are then weakened, in shrinking groups,
+// as long as it stays non-linearizable: reads are dropped, and writes become
+// pending, so they may take effect at any time or not at all. The operations left
+// intact are returned; weakening any one of them alone makes the window
+// linearizable or exhausts the limit.
+func Minimize(ops []Operation, limit int) []Operation {
+	var returns, calls []time.Duration
+	for _, op := range ops {
+		calls = append(calls, op.Call)
+		if !op.Pending {
+			returns = append(returns, op.Return)
+		}
+	}
+	slices.Sort(returns)
+	slices.Sort(calls)
+
+	// End the window at the earliest return that can't be linearized, and start it
+	// at the latest call that keeps it so
+	notLinearizable := func(from, to time.Duration) bool {
+		w, _, known := window(ops, from, to)
+		return check(w, known, limit) == NotLinearizable
+	}
+	to := time.Duration(math.MaxInt64)
+	if i := sort.Search(len(returns), func(i int) bool { return notLinearizable(0, returns[i]) }); i < len(returns) {
+		to = returns[i]
+	}
+	from := time.Duration(0)
+	if i := sort.Search(len(calls), func(i int) bool { return !notLinearizable(calls[i], to) }); i > 0 {
+		from = calls[i-1]
+	}
+
+	current, indexes, known := window(ops, from, to)
+	for size := max(len(current)/2, 1); ; size = max(size/2, 1) {
+		changed := false
+		for start := 0; start < len(current); start += size {
+			trial := slices.Clone(current)
+			weakened := false
+			for i := start; i < min(start+size, len(trial)); i++ {
+				if !trial[i].Pending {
+					trial[i].Pending = true
+					weakened = true
+				}
+			}
+			if weakened && check(trial, known, limit) == NotLinearizable {
+				current = trial
+				changed = true
+			}
+		}
+		// Single operations are retried until none can be weakened
+		if size == 1 && !changed {
+			break
+		}
+	}
+
+	var offending []Operation
+	for i, op := range current {
+		if !op.Pending {
+			offending = append(offending, ops[indexes[i]])
+		}
+	}
+	return offending
+}
+
+// window returns the operations of the history between from and to, with the
+// indexes of the operations and whether the item is known to be absent at from.
+// Operations returning before from and calls after to are left out, and the
+// operations spanning either end become pending. This only allows more orders
+// than the full history, so a window that isn't linearizable shows the history
+// isn't either.
+func window(ops []Operation, from, to time.Duration) ([]Operation, []int, bool) {
+	var w []Operation
+	var indexes []int
+	var known bool
+	for i, op := range ops {
+		if op.Kind == Create && op.Call >= from {
+			// IDs aren't reused, so the item doesn't exist before its create
+			known = true
+		}
+		if op.Call > to || !op.Pending && op.Return < from {
+			continue
+		}
+		if op.Call < from || !op.Pending && op.Return > to {
+			op.Pending = true
+		}
+		w = append(w, op)
+		indexes = append(indexes, i)
+	}
+	return w, indexes, known
+}
@@ -0,0 +1,319 @@
+package linearizability
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ms returns n milliseconds.
+func ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
+
+func create(call, ret int, value string) Operation {
+	return Operation{Kind: Create, Call: ms(call), Return: ms(ret), Input: value, Output: value}
+}
+
+func read(call, ret int, value string) Operation {
+	return Operation{Kind: Read, Call: ms(call), Return: ms(ret), Found: true, Output: value}
+}
+
+func readMissing(call, ret int) Operation {
+	return Operation{Kind: Read, Call: ms(call), Return: ms(ret)}
+}
+
+func update(call, ret int, value string) Operation {
+	return Operation{Kind: Update, Call: ms(call), Return: ms(ret), Input: value, Found: true, Output: value}
+}
+
+func remove(call, ret int) Operation {
+	return Operation{Kind: Delete, Call: ms(call), Return: ms(ret), Found: true}
+}
+
+// pending marks op as never having received a response.
+func pending(op Operation) Operation {
+	op.Pending = true
+	op.Return = 0
+	op.Found = false
+	op.Output = ""
+	return op
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []Operation
+		want Result
+	}{
+		{
+			name: "sequential",
+			ops: []Operation{
+				create(0, 1, "a"),
+				read(2, 3, "a"),
+				update(4, 5, "b"),
+				read(6, 7, "b"),
+				remove(8, 9),
+				readMissing(10, 11),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "concurrent read sees the update",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 6, "b"),
+				read(3, 4, "b"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "concurrent read sees the old value",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 6, "b"),
+				read(3, 4, "a"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "reads disagree on the order of a concurrent update",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 10, "b"),
+				read(3, 4, "b"),
+				read(5, 6, "a"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "stale read",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 3, "b"),
+				read(4, 5, "a"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "read of a value never written",
+			ops: []Operation{
+				create(0, 1, "a"),
+				read(2, 3, "z"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "read after delete",
+			ops: []Operation{
+				create(0, 1, "a"),
+				remove(2, 3),
+				read(4, 5, "a"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "read before create",
+			ops: []Operation{
+				read(0, 1, "a"),
+				create(2, 3, "a"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "update responds with another value",
+			ops: []Operation{
+				create(0, 1, "a"),
+				{Kind: Update, Call: ms(2), Return: ms(3), Input: "b", Found: true, Output: "c"},
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "pending update takes effect",
+			ops: []Operation{
+				create(0, 1, "a"),
+				pending(update(2, 0, "b")),
+				read(10, 11, "b"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "pending update never takes effect",
+			ops: []Operation{
+				create(0, 1, "a"),
+				pending(update(2, 0, "b")),
+				read(10, 11, "a"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "pending update takes effect after a later read",
+			ops: []Operation{
+				create(0, 1, "a"),
+				pending(update(2, 0, "b")),
+				read(10, 11, "a"),
+				read(12, 13, "b"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "pending update can't be undone",
+			ops: []Operation{
+				create(0, 1, "a"),
+				pending(update(2, 0, "b")),
+				read(10, 11, "b"),
+				read(12, 13, "a"),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "pending delete",
+			ops: []Operation{
+				create(0, 1, "a"),
+				pending(remove(2, 0)),
+				readMissing(10, 11),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "pending operations can't take effect before their call",
+			ops: []Operation{
+				create(0, 1, "a"),
+				read(2, 3, "b"),
+				pending(update(4, 0, "b")),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "unknown initial state",
+			ops: []Operation{
+				read(0, 1, "x"),
+				update(2, 3, "y"),
+				read(4, 5, "y"),
+			},
+			want: Linearizable,
+		},
+		{
+			name: "unknown initial state then missing",
+			ops: []Operation{
+				readMissing(0, 1),
+				remove(2, 3),
+			},
+			want: NotLinearizable,
+		},
+		{
+			name: "unknown initial state changes without a write",
+			ops: []Operation{
+				read(0, 1, "x"),
+				read(2, 3, "y"),
+			},
+			want: NotLinearizable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.ops, 0); got != tt.want {
+				t.Errorf("Check() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLimit(t *testing.T) {
+	ops := []Operation{create(0, 1, "a")}
+	for i := 0; i < 10; i++ {
+		ops = append(ops, update(2, 100, string(rune('b'+i))))
+	}
+	ops = append(ops, read(101, 102, "z"))
+
+	if got := Check(ops, 5); got != Unknown {
+		t.Errorf("Check() with a limit = %s, want %s", got, Unknown)
+	}
+	if got := Check(ops, 0); got != NotLinearizable {
+		t.Errorf("Check() without a limit = %s, want %s", got, NotLinearizable)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []Operation
+		want []Operation
+	}{
+		{
+			name: "stale read among reads",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 3, "b"),
+				read(4, 5, "b"),
+				read(6, 7, "b"),
+				read(8, 9, "a"),
+				read(10, 11, "b"),
+				update(12, 13, "c"),
+			},
+			want: []Operation{
+				read(6, 7, "b"),
+				read(8, 9, "a"),
+			},
+		},
+		{
+			name: "read after delete",
+			ops: []Operation{
+				create(0, 1, "a"),
+				read(2, 3, "a"),
+				update(4, 5, "b"),
+				remove(6, 7),
+				read(8, 9, "b"),
+				read(10, 11, "b"),
+			},
+			want: []Operation{
+				remove(6, 7),
+				read(8, 9, "b"),
+			},
+		},
+		{
+			name: "write missed by a read",
+			ops: []Operation{
+				create(0, 1, "a"),
+				update(2, 3, "b"),
+				read(4, 5, "a"),
+			},
+			want: []Operation{
+				update(2, 3, "b"),
+				read(4, 5, "a"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Check(tt.ops, 0) != NotLinearizable {
+				t.Fatalf("Check() = %s, want %s", Check(tt.ops, 0), NotLinearizable)
+			}
+			got := Minimize(tt.ops, 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Minimize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationString(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		want string
+	}{
+		{create(1, 2, "a"), `[1ms, 2ms] create "a" -> "a"`},
+		{read(1, 2, "a"), `[1ms, 2ms] read -> "a"`},
+		{readMissing(1, 2), `[1ms, 2ms] read -> not found`},
+		{update(1, 2, "b"), `[1ms, 2ms] update "b" -> "b"`},
+		{remove(1, 2), `[1ms, 2ms] delete -> ok`},
+		{pending(update(1, 0, "b")), `[1ms, ?] update "b" -> no response`},
+	}
+	for _, tt := range tests {
+		if got := tt.op.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
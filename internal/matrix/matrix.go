@@ -0,0 +1,335 @@
+// Package matrix runs the same workload against several configurations of the
+// embedded server, such as middleware combinations or server implementations,
+// and attributes the differences to the configuration changes.
+package matrix
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+	"github.com/kolosys/helix-stress-test/internal/runner"
+	"github.com/kolosys/helix-stress-test/server"
+)
+
+// Variant is one configuration of the embedded server.
+type Variant struct {
+	Name       string
+	Impl       string // Server implementation
+	Middleware server.Middleware
+}
+
+// Result is the outcome of the runs of one variant. The snapshot is the median
+// run by mean latency, so a single disturbed run doesn't skew the comparison.
+type Result struct {
+	Variant  Variant
+	Snapshot metrics.Snapshot
+	Runs     int    // Runs of the variant
+	Spread   Spread // Variation between the runs (zero for a single run)
+}
+
+// Spread is the sample standard deviation of the results of a variant's runs.
+type Spread struct {
+	RPS         float64
+	LatencyMean time.Duration
+	LatencyP95  time.Duration
+	LatencyP99  time.Duration
+}
+
+// Effect is the change attributable to enabling one middleware: the average
+// difference between the runs with and without it, all else being equal.
+type Effect struct {
+	Middleware     string
+	RPSChange      float64 // Percent
+	MeanDelta      time.Duration
+	P95Delta       time.Duration
+	P99Delta       time.Duration
+	ErrorRateDelta float64 // Percentage points
+}
+
+// MiddlewareVariants returns a variant for every combination of the matrix
+// middleware being enabled or disabled on top of the configured middleware.
+func MiddlewareVariants(cfg *config.Config) []Variant {
+	base := server.MiddlewareOf(cfg.Middleware)
+	n := len(cfg.MatrixMiddleware)
+
+	variants := make([]Variant, 0, 1<<n)
+	for mask := 0; mask < 1<<n; mask++ {
+		mw := base
+		for i, name := range cfg.MatrixMiddleware {
+			mw = mw.With(name, mask&(1<<i) != 0)
+		}
+		variants = append(variants, Variant{Name: mw.String(), Impl: cfg.ServerImpl, Middleware: mw})
+	}
+	return variants
+}
+
+// CompareVariants returns the net/http baseline server and the Helix server with
+// the configured middleware, in that order.
+func CompareVariants(cfg *config.Config) []Variant {
+	return []Variant{
+		{Name: "net/http", Impl: server.ImplNetHTTP},
+		{Name: "helix", Impl: server.ImplHelix, Middleware: server.MiddlewareOf(cfg.Middleware)},
+	}
+}
+
+// Run runs the load test against a fresh embedded server for every variant,
+// cfg.MatrixRepeats times. Each round runs every variant once in an order shuffled
+// with the configured seed, so drift over time (thermal throttling, background
+// load, a warming cache) spreads over all variants instead of favoring the first.
+// onRun is called with the metrics of each run when it starts and returns a
+// function that is called when the run ends. Run stops at the first run that
+// fails and returns the results of the variants run so far, in variant order.
+func Run(ctx context.Context, cfg *config.Config, variants []Variant, onRun func(i int, v Variant, m *metrics.Metrics) func()) ([]Result, error) {
+	runs := make([][]metrics.Snapshot, len(variants))
+	err := runRounds(ctx, cfg, variants, runs, onRun)
+
+	results := make([]Result, 0, len(variants))
+	for idx, v := range variants {
+		if len(runs[idx]) > 0 {
+			results = append(results, summarize(v, runs[idx]))
+		}
+	}
+	return results, err
+}
+
+// runRounds runs the rounds of Run, appending the snapshot of every run of
+// variants[idx] to runs[idx].
+func runRounds(ctx context.Context, cfg *config.Config, variants []Variant, runs [][]metrics.Snapshot, onRun func(i int, v Variant, m *metrics.Metrics) func()) error {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	i := 0
+	for round := 0; round < max(cfg.MatrixRepeats, 1); round++ {
+		for _, idx := range rng.Perm(len(variants)) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			v := variants[idx]
+
+			m := metrics.New()
+			var done func()
+			if onRun != nil {
+				done = onRun(i, v, m)
+			}
+			snapshot, err := runVariant(ctx, cfg, v, m)
+			if done != nil {
+				done()
+			}
+			if err != nil {
+				return fmt.Errorf("variant %s failed: %w", v.Name, err)
+			}
+			runs[idx] = append(runs[idx], snapshot)
+			i++
+		}
+	}
+	return nil
+}
+
+// summarize returns the result of a variant from the snapshots of its runs.
+func summarize(v Variant, runs []metrics.Snapshot) Result {
+	sorted := make([]metrics.Snapshot, len(runs))
+	copy(sorted, runs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].LatencyMean < sorted[j].LatencyMean
+	})
+
+	r := Result{Variant: v, Snapshot: sorted[(len(sorted)-1)/2], Runs: len(runs)}
+	if len(runs) > 1 {
+		r.Spread = Spread{
+			RPS:         stddev(runs, func(s metrics.Snapshot) float64 { return s.AverageRPS }),
+			LatencyMean: time.Duration(stddev(runs, func(s metrics.Snapshot) float64 { return float64(s.LatencyMean) })),
+			LatencyP95:  time.Duration(stddev(runs, func(s metrics.Snapshot) float64 { return float64(s.LatencyP95) })),
+			LatencyP99:  time.Duration(stddev(runs, func(s metrics.Snapshot) float64 { return float64(s.LatencyP99) })),
+		}
+	}
+	return r
+}
+
+// stddev returns the sample standard deviation of a value over the runs.
+func stddev(runs []metrics.Snapshot, value func(metrics.Snapshot) float64) float64 {
+	var sum float64
+	for _, s := range runs {
+		sum += value(s)
+	}
+	mean := sum / float64(len(runs))
+
+	var squares float64
+	for _, s := range runs {
+		d := value(s) - mean
+		squares += d * d
+	}
+	return math.Sqrt(squares / float64(len(runs)-1))
+}
+
+// runVariant starts the embedded server for the variant, runs the load test
+// against it and stops the server. The snapshot is taken before the server stops.
+func runVariant(ctx context.Context, cfg *config.Config, v Variant, m *metrics.Metrics) (metrics.Snapshot, error) {
+	runCfg := *cfg
+	runCfg.TestType = config.TestTypeLoad
+
+	// Trust the generated certificate unless another CA was configured
+	var cert *tls.Certificate
+	if cfg.TLS {
+		generated, certPEM, err := server.GenerateCertificate(cfg.ServerAddr)
+		if err != nil {
+			return metrics.Snapshot{}, err
+		}
+		cert = &generated
+		if cfg.TLSCA == "" {
+			runCfg.TLSRoots = certPEM
+		}
+	}
+
+	serverCtx, serverCancel := context.WithCancel(ctx)
+
+	ready := make(chan struct{})
+	serverErr := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, cleanup, err := server.StartServer(serverCtx, server.Options{
+			Addr:        cfg.ServerAddr,
+			DatasetSize: cfg.DatasetSize,
+			TestType:    string(cfg.TestType),
+			Certificate: cert,
+			Impl:        v.Impl,
+			Middleware:  v.Middleware,
+			Store:       cfg.Store,
+			StoreFile:   cfg.StoreFile,
+			AdminToken:  cfg.AdminToken,
+			Ready:       func() { close(ready) },
+		})
+		if cleanup != nil {
+			cleanup()
+		}
+		serverErr <- err
+	}()
+	defer func() {
+		serverCancel()
+		wg.Wait()
+	}()
+
+	select {
+	case <-ready:
+	case err := <-serverErr:
+		return metrics.Snapshot{}, fmt.Errorf("embedded server failed to start: %w", err)
+	case <-time.After(cfg.ReadyTimeout):
+		return metrics.Snapshot{}, fmt.Errorf("embedded server not ready after %s", cfg.ReadyTimeout)
+	}
+
+	r, err := runner.New(&runCfg, m)
+	if err != nil {
+		return metrics.Snapshot{}, err
+	}
+	if err := r.WaitReady(ctx); err != nil {
+		return metrics.Snapshot{}, err
+	}
+	m.Reset()
+	if err := r.Run(ctx); err != nil {
+		return metrics.Snapshot{}, err
+	}
+	return m.Snapshot(), nil
+}
+
+// MiddlewareEffects attributes the differences between the results to each matrix
+// middleware. Variants are paired by the rest of their middleware, so each effect
+// is averaged over every combination of the other middleware.
+func MiddlewareEffects(names []string, results []Result) []Effect {
+	byMiddleware := make(map[server.Middleware]metrics.Snapshot, len(results))
+	for _, r := range results {
+		byMiddleware[r.Variant.Middleware] = r.Snapshot
+	}
+
+	effects := make([]Effect, 0, len(names))
+	for _, name := range names {
+		e := Effect{Middleware: name}
+		var pairs int
+		var rpsChange, meanDelta, p95Delta, p99Delta, errDelta float64
+		for mw, with := range byMiddleware {
+			if !mw.Enabled(name) {
+				continue
+			}
+			without, ok := byMiddleware[mw.With(name, false)]
+			if !ok {
+				continue
+			}
+			pairs++
+			rpsChange += percentChange(without.AverageRPS, with.AverageRPS)
+			meanDelta += float64(with.LatencyMean - without.LatencyMean)
+			p95Delta += float64(with.LatencyP95 - without.LatencyP95)
+			p99Delta += float64(with.LatencyP99 - without.LatencyP99)
+			errDelta += with.ErrorRate - without.ErrorRate
+		}
+		if pairs > 0 {
+			n := float64(pairs)
+			e.RPSChange = rpsChange / n
+			e.MeanDelta = time.Duration(meanDelta / n)
+			e.P95Delta = time.Duration(p95Delta / n)
+			e.P99Delta = time.Duration(p99Delta / n)
+			e.ErrorRateDelta = errDelta / n
+		}
+		effects = append(effects, e)
+	}
+	return effects
+}
+
+// Overhead compares the statistics of a group of requests, such as an endpoint,
+// between a baseline and another variant.
+type Overhead struct {
+	Name         string
+	BaselineMean time.Duration
+	Mean         time.Duration
+	MeanChange   float64 // Percent
+	BaselineP99  time.Duration
+	P99          time.Duration
+	P99Change    float64 // Percent
+	RPSChange    float64 // Percent
+}
+
+// EndpointOverhead compares every endpoint of result with the same endpoint of the
+// baseline, followed by the totals. Endpoints missing from either run are skipped.
+func EndpointOverhead(baseline, result Result) []Overhead {
+	base := make(map[string]metrics.Stats, len(baseline.Snapshot.Endpoints))
+	for _, e := range baseline.Snapshot.Endpoints {
+		base[e.Name] = e
+	}
+
+	var overheads []Overhead
+	for _, e := range result.Snapshot.Endpoints {
+		if b, ok := base[e.Name]; ok {
+			overheads = append(overheads, overhead(e.Name, b.LatencyMean, e.LatencyMean, b.LatencyP99, e.LatencyP99, b.AverageRPS, e.AverageRPS))
+		}
+	}
+
+	b, s := baseline.Snapshot, result.Snapshot
+	return append(overheads, overhead("total", b.LatencyMean, s.LatencyMean, b.LatencyP99, s.LatencyP99, b.AverageRPS, s.AverageRPS))
+}
+
+// overhead builds the comparison of one group of requests.
+func overhead(name string, baseMean, mean, baseP99, p99 time.Duration, baseRPS, rps float64) Overhead {
+	return Overhead{
+		Name:         name,
+		BaselineMean: baseMean,
+		Mean:         mean,
+		MeanChange:   percentChange(float64(baseMean), float64(mean)),
+		BaselineP99:  baseP99,
+		P99:          p99,
+		P99Change:    percentChange(float64(baseP99), float64(p99)),
+		RPSChange:    percentChange(baseRPS, rps),
+	}
+}
+
+// percentChange returns the change from base to v in percent, or 0 if base is 0.
+func percentChange(base, v float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (v - base) / base * 100
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"slices"
+	"time"
+)
+
+// DatasetSnapshot records the dataset of the server at a point of the test, as
+// reported by its admin snapshot route.
+type DatasetSnapshot struct {
+	At       time.Time
+	Label    string // When it was taken (e.g., "start", "spike", "end")
+	Reseeded bool   // The dataset was reseeded just before
+	Items    int
+	NextID   int
+	Checksum string
+	Error    string `json:",omitempty"` // Why the snapshot or reseed failed
+}
+
+// RecordDataset records a snapshot of the server's dataset.
+func (m *Metrics) RecordDataset(s DatasetSnapshot) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.datasetMu.Lock()
+	defer m.datasetMu.Unlock()
+	m.datasets = append(m.datasets, s)
+}
+
+// datasetSnapshots returns the dataset snapshots in the order they were taken.
+func (m *Metrics) datasetSnapshots() []DatasetSnapshot {
+	m.datasetMu.Lock()
+	defer m.datasetMu.Unlock()
+
+	if len(m.datasets) == 0 {
+		return nil
+	}
+	snapshots := slices.Clone(m.datasets)
+	slices.SortStableFunc(snapshots, func(a, b DatasetSnapshot) int {
+		return a.At.Compare(b.At)
+	})
+	return snapshots
+}
+
+// mergeDatasets adds dataset snapshots exported by another collector.
+func (m *Metrics) mergeDatasets(snapshots []DatasetSnapshot) {
+	m.datasetMu.Lock()
+	defer m.datasetMu.Unlock()
+	m.datasets = append(m.datasets, snapshots...)
+}
@@ -0,0 +1,42 @@
+package metrics
+
+// maxLinearizabilityExamples bounds the violations minimized and described in the report.
+const maxLinearizabilityExamples = 5
+
+// Linearizability is the result of checking the history of every item against a
+// register model after the run.
+type Linearizability struct {
+	Items      int   // Item histories checked
+	Operations int64 // Operations recorded
+	Pending    int64 // Writes without a response, which may or may not have taken effect
+	Violations int   // Histories that aren't linearizable
+	Unknown    int   // Histories whose search was abandoned at the step limit
+
+	Examples []LinearizabilityViolation `json:",omitempty"`
+}
+
+// LinearizabilityViolation describes an item history that isn't linearizable.
+type LinearizabilityViolation struct {
+	Item       int
+	Operations int      // Operations in the history
+	Offending  []string // Minimal operations that can't be linearized together
+}
+
+// WantsExample reports whether another violation should be described.
+func (l *Linearizability) WantsExample() bool {
+	return len(l.Examples) < maxLinearizabilityExamples
+}
+
+// SetLinearizability records the result of the linearizability check.
+func (m *Metrics) SetLinearizability(l Linearizability) {
+	m.verificationMu.Lock()
+	defer m.verificationMu.Unlock()
+	m.linearizability = &l
+}
+
+// linearizabilityResult returns the result of the linearizability check, if any.
+func (m *Metrics) linearizabilityResult() *Linearizability {
+	m.verificationMu.Lock()
+	defer m.verificationMu.Unlock()
+	return m.linearizability
+}
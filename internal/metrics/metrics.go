@@ -10,29 +10,84 @@ import (
 
 // Metrics collects and aggregates performance metrics.
 type Metrics struct {
+	// mu is held for reading while recording and for writing while resetting
 	mu sync.RWMutex
 
 	// Request metrics
-	totalRequests    atomic.Int64
-	successRequests  atomic.Int64
-	errorRequests    atomic.Int64
-	latencies        []time.Duration
-	latenciesMu      sync.Mutex
+	totalRequests   atomic.Int64
+	successRequests atomic.Int64
+	errorRequests   atomic.Int64
+	latencies       []time.Duration
+	latenciesMu     sync.Mutex
 
 	// Error tracking
 	errorsByStatus map[int]int64
 	errorsMu       sync.Mutex
 
 	// Throughput
-	startTime    time.Time
-	lastSecond   time.Time
+	startTime          time.Time
+	endTime            time.Time    // End of the merged states (zero while collecting)
+	merged             bool         // True once a state has been merged
+	lastSecond         atomic.Int64 // Unix nanoseconds of the start of the current second
 	requestsThisSecond atomic.Int64
-	currentRPS   atomic.Int64
+	currentRPS         atomic.Int64
 
 	// Memory metrics
 	initialMemStats runtime.MemStats
 	memStats        runtime.MemStats
 	memStatsMu      sync.Mutex
+
+	// Arrivals of the rate executor, and how long they queued for a free request slot
+	arrivals        atomic.Int64
+	queuedArrivals  atomic.Int64 // Arrivals that found every slot busy
+	queueDelayNanos atomic.Int64
+	maxQueueDelay   atomic.Int64
+
+	// Virtual user iterations
+	iterations     atomic.Int64
+	iterationNanos atomic.Int64
+
+	// Responses by negotiated protocol (e.g., "HTTP/1.1", "HTTP/2.0")
+	protocols   map[string]int64
+	protocolsMu sync.Mutex
+
+	// Connection lifecycle
+	connsOpened atomic.Int64
+	connsReused atomic.Int64
+	connsClosed atomic.Int64
+
+	// Slow client connections by mode
+	slowClients map[string]*SlowClientState
+	slowMu      sync.Mutex
+
+	// Streamed responses by endpoint
+	streams   map[string]*StreamState
+	streamsMu sync.Mutex
+
+	// Snapshots of the server's dataset
+	datasets  []DatasetSnapshot
+	datasetMu sync.Mutex
+
+	// Results of the post-run verification and linearizability check (nil if not run)
+	verification    *Verification
+	linearizability *Linearizability
+	verificationMu  sync.Mutex
+
+	// Requests of the warm-up period (nil without warm-up)
+	warmup *Metrics
+
+	// Per-endpoint breakdown
+	endpoints   map[string]*Metrics
+	endpointsMu sync.Mutex
+
+	// Per-second time series and spike windows (nil for breakdown collectors)
+	series *timeSeries
+
+	// Phase tracking (nil for breakdown collectors)
+	phases     map[string]*Metrics
+	phaseOrder []string
+	phaseMarks []phaseMark
+	phaseMu    sync.RWMutex
 }
 
 // New creates a new Metrics collector.
@@ -41,15 +96,58 @@ func New() *Metrics {
 		latencies:      make([]time.Duration, 0, 10000),
 		errorsByStatus: make(map[int]int64),
 		startTime:      time.Now(),
-		lastSecond:     time.Now(),
+		endpoints:      make(map[string]*Metrics),
+		protocols:      make(map[string]int64),
+		slowClients:    make(map[string]*SlowClientState),
+		streams:        make(map[string]*StreamState),
+		series:         &timeSeries{},
+		phases:         make(map[string]*Metrics),
 	}
 
+	m.lastSecond.Store(m.startTime.UnixNano())
 	runtime.ReadMemStats(&m.initialMemStats)
 	return m
 }
 
+// newChild creates a collector used for a breakdown of the parent metrics.
+// Children only track request and latency statistics.
+func newChild() *Metrics {
+	return &Metrics{
+		latencies:      make([]time.Duration, 0, 1024),
+		errorsByStatus: make(map[int]int64),
+		startTime:      time.Now(),
+	}
+}
+
+// Endpoint returns the collector for the named endpoint, creating it on first use.
+// Requests recorded on the returned collector are reported in the endpoint breakdown
+// and are not added to the parent totals.
+func (m *Metrics) Endpoint(name string) *Metrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.endpointsMu.Lock()
+	defer m.endpointsMu.Unlock()
+
+	child, ok := m.endpoints[name]
+	if !ok {
+		child = newChild()
+		child.startTime = m.startTime
+		m.endpoints[name] = child
+	}
+	return child
+}
+
 // RecordRequest records a request with its latency and status code.
+// Requests sent before the collector was last reset are ignored.
 func (m *Metrics) RecordRequest(latency time.Duration, statusCode int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	if now.Add(-latency).Before(m.startTime) {
+		return
+	}
+
 	m.totalRequests.Add(1)
 
 	if statusCode >= 200 && statusCode < 400 {
@@ -66,48 +164,166 @@ func (m *Metrics) RecordRequest(latency time.Duration, statusCode int) {
 	m.latenciesMu.Unlock()
 
 	// Update RPS calculation
-	now := time.Now()
-	if now.Sub(m.lastSecond) >= time.Second {
-		m.currentRPS.Store(m.requestsThisSecond.Load())
-		m.requestsThisSecond.Store(0)
-		m.lastSecond = now
+	if m.series != nil {
+		m.series.record(m.startTime, now, latency, true, statusCode < 200 || statusCode >= 400)
+	}
+	if phase := m.phaseAt(now.Add(-latency)); phase != nil {
+		phase.RecordRequest(latency, statusCode)
+	}
+	last := m.lastSecond.Load()
+	if now.UnixNano()-last >= int64(time.Second) {
+		if m.lastSecond.CompareAndSwap(last, now.UnixNano()) {
+			m.currentRPS.Store(m.requestsThisSecond.Swap(0))
+		}
 	} else {
 		m.requestsThisSecond.Add(1)
 	}
 }
 
-// RecordError records an error response.
-func (m *Metrics) RecordError(statusCode int) {
+// RecordIteration records a completed virtual user iteration.
+func (m *Metrics) RecordIteration(d time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.iterations.Add(1)
+	m.iterationNanos.Add(int64(d))
+}
+
+// RecordArrival records an arrival sent delay after its scheduled time. queued
+// reports whether it found every request slot busy and waited for one.
+func (m *Metrics) RecordArrival(delay time.Duration, queued bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.arrivals.Add(1)
+	if queued {
+		m.queuedArrivals.Add(1)
+	}
+	m.queueDelayNanos.Add(int64(delay))
+	for {
+		current := m.maxQueueDelay.Load()
+		if int64(delay) <= current || m.maxQueueDelay.CompareAndSwap(current, int64(delay)) {
+			break
+		}
+	}
+}
+
+// RecordProtocol records the protocol a response was received over.
+func (m *Metrics) RecordProtocol(proto string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.protocolsMu.Lock()
+	m.protocols[proto]++
+	m.protocolsMu.Unlock()
+}
+
+// RecordConnOpened records a newly dialed connection.
+func (m *Metrics) RecordConnOpened() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.connsOpened.Add(1)
+}
+
+// RecordConnReused records a request sent over a previously used connection.
+func (m *Metrics) RecordConnReused() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.connsReused.Add(1)
+}
+
+// RecordConnClosed records a closed connection.
+func (m *Metrics) RecordConnClosed() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.connsClosed.Add(1)
+}
+
+// RecordError records a request that failed after latency without a usable
+// response. Like RecordRequest, it is attributed to the phase it was sent in, and
+// requests sent before the collector was last reset are ignored.
+func (m *Metrics) RecordError(latency time.Duration, statusCode int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	if now.Add(-latency).Before(m.startTime) {
+		return
+	}
+
 	m.errorRequests.Add(1)
 	m.errorsMu.Lock()
 	m.errorsByStatus[statusCode]++
 	m.errorsMu.Unlock()
+
+	if m.series != nil {
+		m.series.record(m.startTime, now, 0, false, true)
+	}
+	if phase := m.phaseAt(now.Add(-latency)); phase != nil {
+		phase.RecordError(latency, statusCode)
+	}
 }
 
 // Snapshot captures a snapshot of current metrics.
 type Snapshot struct {
-	StartTime         time.Time
-	EndTime           time.Time
-	Duration          time.Duration
-	TotalRequests     int64
-	SuccessRequests   int64
-	ErrorRequests     int64
-	CurrentRPS        int64
-	AverageRPS        float64
-	LatencyP50        time.Duration
-	LatencyP95        time.Duration
-	LatencyP99        time.Duration
-	LatencyP999       time.Duration
-	LatencyMin        time.Duration
-	LatencyMax        time.Duration
-	LatencyMean       time.Duration
-	ErrorsByStatus    map[int]int64
-	ErrorRate         float64
-	MemoryAllocated   uint64
-	MemoryTotalAlloc  uint64
-	MemorySys         uint64
-	NumGC             uint32
-	GCPercent         float64
+	StartTime        time.Time
+	EndTime          time.Time
+	Duration         time.Duration
+	TotalRequests    int64
+	SuccessRequests  int64
+	ErrorRequests    int64
+	CurrentRPS       int64
+	AverageRPS       float64
+	LatencyP50       time.Duration
+	LatencyP95       time.Duration
+	LatencyP99       time.Duration
+	LatencyP999      time.Duration
+	LatencyMin       time.Duration
+	LatencyMax       time.Duration
+	LatencyMean      time.Duration
+	ErrorsByStatus   map[int]int64
+	ErrorRate        float64
+	Warmup           *Stats           `json:",omitempty"` // Excluded from the results above
+	Protocols        map[string]int64 `json:",omitempty"` // Responses by negotiated protocol
+	ConnsOpened      int64            // Connections dialed
+	ConnsReused      int64            // Requests sent over a previously used connection
+	ConnsClosed      int64            // Connections closed, including ones opened during warm-up
+	MemoryAllocated  uint64
+	MemoryTotalAlloc uint64
+	MemorySys        uint64
+	NumGC            uint32
+	GCPercent        float64
+	Arrivals         int64             `json:",omitempty"` // Arrivals sent by the rate executor
+	QueuedArrivals   int64             `json:",omitempty"` // Arrivals that waited for a free request slot
+	QueueDelayMean   time.Duration     `json:",omitempty"` // Mean delay from the scheduled time of an arrival to its send
+	QueueDelayMax    time.Duration     `json:",omitempty"`
+	Iterations       int64             `json:",omitempty"`
+	IterationMean    time.Duration     `json:",omitempty"`
+	Endpoints        []Stats           `json:",omitempty"`
+	SlowClients      []SlowClientStats `json:",omitempty"`
+	Streams          []StreamStats     `json:",omitempty"`
+	Datasets         []DatasetSnapshot `json:",omitempty"`
+	Verification     *Verification     `json:",omitempty"`
+	Linearizability  *Linearizability  `json:",omitempty"`
+	Timeline         []Bucket          `json:",omitempty"`
+	Spikes           []SpikeResult     `json:",omitempty"`
+	Phases           []PhaseStats      `json:",omitempty"`
+}
+
+// Stats captures request and latency statistics for a breakdown group such as an endpoint.
+type Stats struct {
+	Name            string
+	TotalRequests   int64
+	SuccessRequests int64
+	ErrorRequests   int64
+	AverageRPS      float64
+	LatencyP50      time.Duration
+	LatencyP95      time.Duration
+	LatencyP99      time.Duration
+	LatencyP999     time.Duration
+	LatencyMin      time.Duration
+	LatencyMax      time.Duration
+	LatencyMean     time.Duration
+	ErrorsByStatus  map[int]int64
+	ErrorRate       float64
 }
 
 // Snapshot captures the current state of metrics.
@@ -117,9 +333,128 @@ func (m *Metrics) Snapshot() Snapshot {
 	memStats := m.memStats
 	m.memStatsMu.Unlock()
 
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	if !m.endTime.IsZero() {
+		now = m.endTime
+	}
+	stats := m.stats("", now)
+	var warmup *Stats
+	if m.warmup != nil {
+		w := m.warmup.stats("warm-up", m.warmup.endTime)
+		warmup = &w
+	}
+
+	arrivals := m.arrivals.Load()
+	var queueDelayMean time.Duration
+	if arrivals > 0 {
+		queueDelayMean = time.Duration(m.queueDelayNanos.Load() / arrivals)
+	}
+
+	iterations := m.iterations.Load()
+	var iterationMean time.Duration
+	if iterations > 0 {
+		iterationMean = time.Duration(m.iterationNanos.Load() / iterations)
+	}
+
+	var timeline []Bucket
+	var spikes []SpikeResult
+	if m.series != nil {
+		timeline = m.timeline(now)
+		spikes = m.spikeResults(timeline)
+	}
+
+	return Snapshot{
+		StartTime:        m.startTime,
+		EndTime:          now,
+		Duration:         now.Sub(m.startTime),
+		TotalRequests:    stats.TotalRequests,
+		SuccessRequests:  stats.SuccessRequests,
+		ErrorRequests:    stats.ErrorRequests,
+		CurrentRPS:       m.currentRPS.Load(),
+		AverageRPS:       stats.AverageRPS,
+		LatencyP50:       stats.LatencyP50,
+		LatencyP95:       stats.LatencyP95,
+		LatencyP99:       stats.LatencyP99,
+		LatencyP999:      stats.LatencyP999,
+		LatencyMin:       stats.LatencyMin,
+		LatencyMax:       stats.LatencyMax,
+		LatencyMean:      stats.LatencyMean,
+		ErrorsByStatus:   stats.ErrorsByStatus,
+		ErrorRate:        stats.ErrorRate,
+		Warmup:           warmup,
+		Protocols:        m.protocolCounts(),
+		ConnsOpened:      m.connsOpened.Load(),
+		ConnsReused:      m.connsReused.Load(),
+		ConnsClosed:      m.connsClosed.Load(),
+		MemoryAllocated:  growth(memStats.Alloc, m.initialMemStats.Alloc),
+		MemoryTotalAlloc: memStats.TotalAlloc - m.initialMemStats.TotalAlloc,
+		MemorySys:        growth(memStats.Sys, m.initialMemStats.Sys),
+		NumGC:            memStats.NumGC - m.initialMemStats.NumGC,
+		GCPercent:        float64(memStats.NumGC-m.initialMemStats.NumGC) / now.Sub(m.startTime).Seconds() * 60,
+		Arrivals:         arrivals,
+		QueuedArrivals:   m.queuedArrivals.Load(),
+		QueueDelayMean:   queueDelayMean,
+		QueueDelayMax:    time.Duration(m.maxQueueDelay.Load()),
+		Iterations:       iterations,
+		IterationMean:    iterationMean,
+		Endpoints:        m.endpointStats(now),
+		SlowClients:      m.slowClientStats(),
+		Streams:          m.streamStats(),
+		Datasets:         m.datasetSnapshots(),
+		Verification:     m.verificationResult(),
+		Linearizability:  m.linearizabilityResult(),
+		Timeline:         timeline,
+		Spikes:           spikes,
+		Phases:           m.phaseStats(now),
+	}
+}
+
+// protocolCounts returns a copy of the responses by protocol.
+func (m *Metrics) protocolCounts() map[string]int64 {
+	m.protocolsMu.Lock()
+	defer m.protocolsMu.Unlock()
+
+	if len(m.protocols) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(m.protocols))
+	for proto, n := range m.protocols {
+		counts[proto] = n
+	}
+	return counts
+}
+
+// endpointStats returns the statistics of every endpoint, ordered by name.
+func (m *Metrics) endpointStats(now time.Time) []Stats {
+	m.endpointsMu.Lock()
+	names := make([]string, 0, len(m.endpoints))
+	children := make(map[string]*Metrics, len(m.endpoints))
+	for name, child := range m.endpoints {
+		names = append(names, name)
+		children[name] = child
+	}
+	m.endpointsMu.Unlock()
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	result := make([]Stats, 0, len(names))
+	for _, name := range names {
+		result = append(result, children[name].stats(name, now))
+	}
+	return result
+}
+
+// stats computes the request and latency statistics of the collector.
+func (m *Metrics) stats(name string, now time.Time) Stats {
 	m.latenciesMu.Lock()
-	latencies := make([]time.Duration, len(m.latencies))
-	copy(latencies, m.latencies)
+	sorted := make([]time.Duration, len(m.latencies))
+	copy(sorted, m.latencies)
 	m.latenciesMu.Unlock()
 
 	m.errorsMu.Lock()
@@ -130,72 +465,55 @@ func (m *Metrics) Snapshot() Snapshot {
 	m.errorsMu.Unlock()
 
 	total := m.totalRequests.Load()
-	success := m.successRequests.Load()
 	errors := m.errorRequests.Load()
-	now := time.Now()
 	duration := now.Sub(m.startTime)
 
-	var (
-		latencyP50, latencyP95, latencyP99, latencyP999 time.Duration
-		latencyMin, latencyMax, latencyMean              time.Duration
-	)
+	s := Stats{
+		Name:            name,
+		TotalRequests:   total,
+		SuccessRequests: m.successRequests.Load(),
+		ErrorRequests:   errors,
+		ErrorsByStatus:  errorsByStatus,
+	}
 
-	if len(latencies) > 0 {
-		sorted := make([]time.Duration, len(latencies))
-		copy(sorted, latencies)
+	if len(sorted) > 0 {
 		sort.Slice(sorted, func(i, j int) bool {
 			return sorted[i] < sorted[j]
 		})
 
-		latencyMin = sorted[0]
-		latencyMax = sorted[len(sorted)-1]
+		s.LatencyMin = sorted[0]
+		s.LatencyMax = sorted[len(sorted)-1]
 
 		var sum time.Duration
 		for _, l := range sorted {
 			sum += l
 		}
-		latencyMean = sum / time.Duration(len(sorted))
+		s.LatencyMean = sum / time.Duration(len(sorted))
 
-		latencyP50 = percentile(sorted, 0.50)
-		latencyP95 = percentile(sorted, 0.95)
-		latencyP99 = percentile(sorted, 0.99)
-		latencyP999 = percentile(sorted, 0.999)
+		s.LatencyP50 = percentile(sorted, 0.50)
+		s.LatencyP95 = percentile(sorted, 0.95)
+		s.LatencyP99 = percentile(sorted, 0.99)
+		s.LatencyP999 = percentile(sorted, 0.999)
 	}
 
-	var errorRate float64
 	if total > 0 {
-		errorRate = float64(errors) / float64(total) * 100
+		s.ErrorRate = float64(errors) / float64(total) * 100
 	}
 
-	var avgRPS float64
 	if duration > 0 {
-		avgRPS = float64(total) / duration.Seconds()
+		s.AverageRPS = float64(total) / duration.Seconds()
 	}
 
-	return Snapshot{
-		StartTime:        m.startTime,
-		EndTime:          now,
-		Duration:         duration,
-		TotalRequests:    total,
-		SuccessRequests:  success,
-		ErrorRequests:    errors,
-		CurrentRPS:       m.currentRPS.Load(),
-		AverageRPS:       avgRPS,
-		LatencyP50:       latencyP50,
-		LatencyP95:       latencyP95,
-		LatencyP99:       latencyP99,
-		LatencyP999:      latencyP999,
-		LatencyMin:       latencyMin,
-		LatencyMax:       latencyMax,
-		LatencyMean:      latencyMean,
-		ErrorsByStatus:   errorsByStatus,
-		ErrorRate:        errorRate,
-		MemoryAllocated:  memStats.Alloc - m.initialMemStats.Alloc,
-		MemoryTotalAlloc: memStats.TotalAlloc - m.initialMemStats.TotalAlloc,
-		MemorySys:        memStats.Sys - m.initialMemStats.Sys,
-		NumGC:            memStats.NumGC - m.initialMemStats.NumGC,
-		GCPercent:        float64(memStats.NumGC-m.initialMemStats.NumGC) / duration.Seconds() * 60,
+	return s
+}
+
+// growth returns how much a memory statistic grew, or zero if it shrank
+// (e.g., after a garbage collection following a reset).
+func growth(current, initial uint64) uint64 {
+	if current < initial {
+		return 0
 	}
+	return current - initial
 }
 
 // percentile calculates the percentile value from a sorted slice.
@@ -210,11 +528,45 @@ func percentile(sorted []time.Duration, p float64) time.Duration {
 	return sorted[index]
 }
 
-// Reset clears all metrics.
+// Reset clears all metrics. It is safe to call while requests are being recorded;
+// requests sent before the reset are ignored when they complete.
 func (m *Metrics) Reset() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.reset()
+	m.warmup = nil
+
+	// Dataset snapshots span the warm-up, so only a full reset clears them
+	m.datasetMu.Lock()
+	m.datasets = nil
+	m.datasetMu.Unlock()
+
+	m.verificationMu.Lock()
+	m.verification = nil
+	m.linearizability = nil
+	m.verificationMu.Unlock()
+}
+
+// EndWarmup ends the warm-up period: the requests and latencies collected so far
+// are kept as the warm-up summary and all other metrics are reset.
+func (m *Metrics) EndWarmup() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	s := m.export(now)
+	s.Endpoints = nil
+	warmup := newChild()
+	warmup.startTime, warmup.endTime = m.startTime, now
+	warmup.merge(s)
+
+	m.reset()
+	m.warmup = warmup
+}
+
+// reset clears all metrics. The caller must hold mu.
+func (m *Metrics) reset() {
 	m.totalRequests.Store(0)
 	m.successRequests.Store(0)
 	m.errorRequests.Store(0)
@@ -228,10 +580,44 @@ func (m *Metrics) Reset() {
 	m.errorsMu.Unlock()
 
 	m.startTime = time.Now()
-	m.lastSecond = time.Now()
+	m.endTime = time.Time{}
+	m.merged = false
+	m.lastSecond.Store(m.startTime.UnixNano())
 	m.requestsThisSecond.Store(0)
 	m.currentRPS.Store(0)
+	m.arrivals.Store(0)
+	m.queuedArrivals.Store(0)
+	m.queueDelayNanos.Store(0)
+	m.maxQueueDelay.Store(0)
+	m.iterations.Store(0)
+	m.iterationNanos.Store(0)
+	m.connsOpened.Store(0)
+	m.connsReused.Store(0)
+	m.connsClosed.Store(0)
+
+	m.endpointsMu.Lock()
+	m.endpoints = make(map[string]*Metrics)
+	m.endpointsMu.Unlock()
+
+	m.protocolsMu.Lock()
+	m.protocols = make(map[string]int64)
+	m.protocolsMu.Unlock()
+
+	m.slowMu.Lock()
+	m.slowClients = make(map[string]*SlowClientState)
+	m.slowMu.Unlock()
+
+	m.streamsMu.Lock()
+	m.streams = make(map[string]*StreamState)
+	m.streamsMu.Unlock()
+
+	m.series = &timeSeries{}
+
+	m.phaseMu.Lock()
+	m.phases = make(map[string]*Metrics)
+	m.phaseOrder = nil
+	m.phaseMarks = nil
+	m.phaseMu.Unlock()
 
 	runtime.ReadMemStats(&m.initialMemStats)
 }
-
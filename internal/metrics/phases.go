@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// PhaseBaseline is the name of the phase other phases are compared against.
+const PhaseBaseline = "baseline"
+
+// PhaseStats captures the statistics of a test phase. A phase may span several
+// intervals (e.g., the baseline between spikes); its statistics cover all of them.
+type PhaseStats struct {
+	Stats
+	Duration time.Duration // Total time spent in the phase
+
+	// Degradation relative to the baseline phase (zero if there is no baseline)
+	MeanRatio      float64 `json:",omitempty"`
+	P95Ratio       float64 `json:",omitempty"`
+	P99Ratio       float64 `json:",omitempty"`
+	ErrorRateDelta float64 `json:",omitempty"` // Percentage points
+}
+
+// phaseMark records the start of a phase.
+type phaseMark struct {
+	at   time.Time
+	name string
+}
+
+// SetPhase starts the named phase. Requests are attributed to the phase that was
+// active when they were sent. An empty name ends phase tracking until the next call.
+func (m *Metrics) SetPhase(name string) {
+	m.phaseMu.Lock()
+	defer m.phaseMu.Unlock()
+	m.setPhaseAt(name, time.Now())
+}
+
+// SwitchPhase starts the phase to if the phase from is still active, and reports whether it did.
+func (m *Metrics) SwitchPhase(from, to string) bool {
+	m.phaseMu.Lock()
+	defer m.phaseMu.Unlock()
+
+	if len(m.phaseMarks) == 0 || m.phaseMarks[len(m.phaseMarks)-1].name != from {
+		return false
+	}
+	m.setPhaseAt(to, time.Now())
+	return true
+}
+
+// setPhaseAt records the start of a phase at the given time, or only registers the
+// phase if the time is zero. The caller must hold phaseMu.
+func (m *Metrics) setPhaseAt(name string, at time.Time) {
+	if !at.IsZero() {
+		m.phaseMarks = append(m.phaseMarks, phaseMark{at: at, name: name})
+	}
+	if name == "" {
+		return
+	}
+	if _, ok := m.phases[name]; !ok {
+		child := newChild()
+		child.startTime = m.startTime
+		m.phases[name] = child
+		m.phaseOrder = append(m.phaseOrder, name)
+	}
+}
+
+// phaseAt returns the collector of the phase active at t, or nil if no phase was active.
+func (m *Metrics) phaseAt(t time.Time) *Metrics {
+	if m.phases == nil {
+		return nil
+	}
+
+	m.phaseMu.RLock()
+	defer m.phaseMu.RUnlock()
+
+	i := sort.Search(len(m.phaseMarks), func(i int) bool {
+		return m.phaseMarks[i].at.After(t)
+	})
+	if i == 0 {
+		return nil
+	}
+	name := m.phaseMarks[i-1].name
+	if name == "" {
+		return nil
+	}
+	return m.phases[name]
+}
+
+// phaseStats returns the statistics of every phase in the order the phases first started.
+func (m *Metrics) phaseStats(now time.Time) []PhaseStats {
+	if m.phases == nil {
+		return nil
+	}
+
+	m.phaseMu.RLock()
+	order := append([]string(nil), m.phaseOrder...)
+	children := make(map[string]*Metrics, len(m.phases))
+	for name, child := range m.phases {
+		children[name] = child
+	}
+	durations := make(map[string]time.Duration, len(m.phases))
+	for i, mark := range m.phaseMarks {
+		end := now
+		if i+1 < len(m.phaseMarks) {
+			end = m.phaseMarks[i+1].at
+		}
+		if mark.name != "" {
+			durations[mark.name] += end.Sub(mark.at)
+		}
+	}
+	m.phaseMu.RUnlock()
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	result := make([]PhaseStats, 0, len(order))
+	var baseline *PhaseStats
+	for _, name := range order {
+		p := PhaseStats{
+			Stats:    children[name].stats(name, now),
+			Duration: durations[name],
+		}
+		p.AverageRPS = 0
+		if p.Duration > 0 {
+			p.AverageRPS = float64(p.TotalRequests) / p.Duration.Seconds()
+		}
+		result = append(result, p)
+	}
+
+	for i := range result {
+		if result[i].Name == PhaseBaseline {
+			baseline = &result[i]
+			break
+		}
+	}
+	if baseline == nil || baseline.TotalRequests == 0 {
+		return result
+	}
+
+	for i := range result {
+		p := &result[i]
+		if p.Name == PhaseBaseline || p.TotalRequests == 0 {
+			continue
+		}
+		p.MeanRatio = ratio(p.LatencyMean, baseline.LatencyMean)
+		p.P95Ratio = ratio(p.LatencyP95, baseline.LatencyP95)
+		p.P99Ratio = ratio(p.LatencyP99, baseline.LatencyP99)
+		p.ErrorRateDelta = p.ErrorRate - baseline.ErrorRate
+	}
+	return result
+}
+
+// ratio returns a/b, or zero if b is zero.
+func ratio(a, b time.Duration) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
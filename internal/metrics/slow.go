@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// SlowOutcome describes how a slow client connection ended.
+type SlowOutcome string
+
+const (
+	SlowCompleted      SlowOutcome = "completed"        // The server answered the request
+	SlowClosedByServer SlowOutcome = "closed by server" // The server closed or reset the connection first
+	SlowHeld           SlowOutcome = "held"             // The connection was still open when the test ended
+	SlowFailed         SlowOutcome = "failed"           // The connection could not be opened
+)
+
+// SlowClientState holds the raw totals of the slow clients of one mode.
+type SlowClientState struct {
+	Connections    int64
+	Completed      int64
+	ClosedByServer int64
+	Held           int64
+	Failed         int64
+	HeldNanos      int64 // Total time the connections were open
+	MaxHeldNanos   int64
+}
+
+// SlowClientStats summarizes the slow clients of one mode.
+type SlowClientStats struct {
+	Mode           string
+	Connections    int64 // Connections opened
+	Completed      int64 // Requests the server answered
+	ClosedByServer int64 // Connections the server closed or reset before the request completed
+	Held           int64 // Connections still open when the test ended
+	Failed         int64 // Connections that could not be opened
+	MeanHeld       time.Duration
+	MaxHeld        time.Duration
+}
+
+// RecordSlowClient records the end of a slow client connection of the given mode
+// that was open for held.
+func (m *Metrics) RecordSlowClient(mode string, outcome SlowOutcome, held time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+
+	s, ok := m.slowClients[mode]
+	if !ok {
+		s = &SlowClientState{}
+		m.slowClients[mode] = s
+	}
+	switch outcome {
+	case SlowFailed:
+		s.Failed++
+		return
+	case SlowCompleted:
+		s.Completed++
+	case SlowClosedByServer:
+		s.ClosedByServer++
+	case SlowHeld:
+		s.Held++
+	}
+	s.Connections++
+	s.HeldNanos += int64(held)
+	s.MaxHeldNanos = max(s.MaxHeldNanos, int64(held))
+}
+
+// slowClientStates returns a copy of the slow client totals by mode.
+func (m *Metrics) slowClientStates() map[string]SlowClientState {
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+
+	if len(m.slowClients) == 0 {
+		return nil
+	}
+	states := make(map[string]SlowClientState, len(m.slowClients))
+	for mode, s := range m.slowClients {
+		states[mode] = *s
+	}
+	return states
+}
+
+// mergeSlowClients adds slow client totals to the collector.
+func (m *Metrics) mergeSlowClients(states map[string]SlowClientState) {
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+
+	for mode, src := range states {
+		dst, ok := m.slowClients[mode]
+		if !ok {
+			dst = &SlowClientState{}
+			m.slowClients[mode] = dst
+		}
+		dst.Connections += src.Connections
+		dst.Completed += src.Completed
+		dst.ClosedByServer += src.ClosedByServer
+		dst.Held += src.Held
+		dst.Failed += src.Failed
+		dst.HeldNanos += src.HeldNanos
+		dst.MaxHeldNanos = max(dst.MaxHeldNanos, src.MaxHeldNanos)
+	}
+}
+
+// slowClientStats returns the statistics of every slow client mode, ordered by mode.
+func (m *Metrics) slowClientStats() []SlowClientStats {
+	states := m.slowClientStates()
+	if len(states) == 0 {
+		return nil
+	}
+
+	result := make([]SlowClientStats, 0, len(states))
+	for mode, s := range states {
+		stats := SlowClientStats{
+			Mode:           mode,
+			Connections:    s.Connections,
+			Completed:      s.Completed,
+			ClosedByServer: s.ClosedByServer,
+			Held:           s.Held,
+			Failed:         s.Failed,
+			MaxHeld:        time.Duration(s.MaxHeldNanos),
+		}
+		if s.Connections > 0 {
+			stats.MeanHeld = time.Duration(s.HeldNanos / s.Connections)
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Mode < result[j].Mode })
+	return result
+}
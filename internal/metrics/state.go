@@ -0,0 +1,324 @@
+package metrics
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramSubBits is the number of bits of precision kept for each power of two
+// in the latency histogram, which bounds the relative error to under 1%.
+const histogramSubBits = 7
+
+// State is the raw state of a collector. Distributed agents export their state so
+// the coordinator can merge it into one report; latencies are kept as a histogram.
+type State struct {
+	StartTime       time.Time
+	EndTime         time.Time
+	TotalRequests   int64
+	SuccessRequests int64
+	ErrorRequests   int64
+	ErrorsByStatus  map[int]int64
+	Latencies       map[int64]int64            `json:",omitempty"` // Histogram bucket index to count
+	Arrivals        int64                      `json:",omitempty"`
+	QueuedArrivals  int64                      `json:",omitempty"`
+	QueueDelayNanos int64                      `json:",omitempty"`
+	MaxQueueDelay   int64                      `json:",omitempty"`
+	Iterations      int64                      `json:",omitempty"`
+	IterationNanos  int64                      `json:",omitempty"`
+	Protocols       map[string]int64           `json:",omitempty"`
+	ConnsOpened     int64                      `json:",omitempty"`
+	ConnsReused     int64                      `json:",omitempty"`
+	ConnsClosed     int64                      `json:",omitempty"`
+	Endpoints       map[string]State           `json:",omitempty"`
+	SlowClients     map[string]SlowClientState `json:",omitempty"`
+	Streams         map[string]StreamState     `json:",omitempty"`
+	Datasets        []DatasetSnapshot          `json:",omitempty"`
+	Phases          map[string]State           `json:",omitempty"`
+	PhaseMarks      []PhaseMark                `json:",omitempty"`
+	Series          []SeriesBucket             `json:",omitempty"`
+	Spikes          []SpikeWindow              `json:",omitempty"`
+	Warmup          *State                     `json:",omitempty"` // Requests of the warm-up period
+}
+
+// PhaseMark records the start of a phase.
+type PhaseMark struct {
+	At   time.Time
+	Name string
+}
+
+// SeriesBucket holds the raw totals of one second of the time series.
+type SeriesBucket struct {
+	Requests     int64
+	Errors       int64
+	LatencyCount int64
+	LatencyNanos int64
+}
+
+// SpikeWindow records when a spike ran.
+type SpikeWindow struct {
+	Start   time.Time
+	End     time.Time
+	PeakRPS int
+	Shape   string
+}
+
+// Export returns the state of the collector.
+func (m *Metrics) Export() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	s := m.export(now)
+	s.Protocols = m.protocolCounts()
+	s.ConnsOpened = m.connsOpened.Load()
+	s.ConnsReused = m.connsReused.Load()
+	s.ConnsClosed = m.connsClosed.Load()
+	s.SlowClients = m.slowClientStates()
+	s.Streams = m.streamStates()
+	s.Datasets = m.datasetSnapshots()
+	if m.warmup != nil {
+		w := m.warmup.export(m.warmup.endTime)
+		s.Warmup = &w
+	}
+
+	if m.series != nil {
+		m.series.mu.Lock()
+		s.Series = make([]SeriesBucket, len(m.series.buckets))
+		for i, b := range m.series.buckets {
+			s.Series[i] = SeriesBucket{
+				Requests:     b.requests,
+				Errors:       b.errors,
+				LatencyCount: b.latencyCount,
+				LatencyNanos: b.latencyNanos,
+			}
+		}
+		for _, w := range m.series.spikes {
+			if w.finished {
+				s.Spikes = append(s.Spikes, SpikeWindow{Start: w.start, End: w.end, PeakRPS: w.peakRPS, Shape: w.shape})
+			}
+		}
+		m.series.mu.Unlock()
+	}
+
+	if m.phases != nil {
+		m.phaseMu.RLock()
+		for _, mark := range m.phaseMarks {
+			s.PhaseMarks = append(s.PhaseMarks, PhaseMark{At: mark.at, Name: mark.name})
+		}
+		phases := make(map[string]*Metrics, len(m.phases))
+		for name, child := range m.phases {
+			phases[name] = child
+		}
+		m.phaseMu.RUnlock()
+
+		for name, child := range phases {
+			if s.Phases == nil {
+				s.Phases = make(map[string]State, len(phases))
+			}
+			s.Phases[name] = child.export(now)
+		}
+	}
+
+	return s
+}
+
+// export returns the request, latency and endpoint state of the collector.
+func (m *Metrics) export(now time.Time) State {
+	s := State{
+		StartTime:       m.startTime,
+		EndTime:         now,
+		TotalRequests:   m.totalRequests.Load(),
+		SuccessRequests: m.successRequests.Load(),
+		ErrorRequests:   m.errorRequests.Load(),
+		ErrorsByStatus:  make(map[int]int64),
+		Latencies:       make(map[int64]int64),
+		Arrivals:        m.arrivals.Load(),
+		QueuedArrivals:  m.queuedArrivals.Load(),
+		QueueDelayNanos: m.queueDelayNanos.Load(),
+		MaxQueueDelay:   m.maxQueueDelay.Load(),
+		Iterations:      m.iterations.Load(),
+		IterationNanos:  m.iterationNanos.Load(),
+	}
+
+	m.latenciesMu.Lock()
+	for _, l := range m.latencies {
+		s.Latencies[histogramIndex(l)]++
+	}
+	m.latenciesMu.Unlock()
+
+	m.errorsMu.Lock()
+	for k, v := range m.errorsByStatus {
+		s.ErrorsByStatus[k] = v
+	}
+	m.errorsMu.Unlock()
+
+	m.endpointsMu.Lock()
+	children := make(map[string]*Metrics, len(m.endpoints))
+	for name, child := range m.endpoints {
+		children[name] = child
+	}
+	m.endpointsMu.Unlock()
+
+	for name, child := range children {
+		if s.Endpoints == nil {
+			s.Endpoints = make(map[string]State, len(children))
+		}
+		s.Endpoints[name] = child.export(now)
+	}
+	return s
+}
+
+// Merge adds an exported state to the collector. The first merged state sets the
+// start of the test; the time series of later states are aligned to it. Phase marks
+// and spike windows are taken from the first state that has them, since every agent
+// runs the same schedule, and the spike peaks of all states are added up. Warm-up
+// requests are merged into one warm-up summary spanning every agent's warm-up.
+func (m *Metrics) Merge(s State) {
+	m.mu.Lock()
+	if !m.merged {
+		m.merged = true
+		m.startTime = s.StartTime
+		m.endTime = s.EndTime
+	} else if s.EndTime.After(m.endTime) {
+		m.endTime = s.EndTime
+	}
+	if w := s.Warmup; w != nil {
+		if m.warmup == nil {
+			m.warmup = newChild()
+			m.warmup.startTime, m.warmup.endTime = w.StartTime, w.EndTime
+		}
+		if w.StartTime.Before(m.warmup.startTime) {
+			m.warmup.startTime = w.StartTime
+		}
+		if w.EndTime.After(m.warmup.endTime) {
+			m.warmup.endTime = w.EndTime
+		}
+		m.warmup.merge(*w)
+	}
+	m.mu.Unlock()
+
+	m.merge(s)
+
+	m.protocolsMu.Lock()
+	for proto, n := range s.Protocols {
+		m.protocols[proto] += n
+	}
+	m.protocolsMu.Unlock()
+
+	m.connsOpened.Add(s.ConnsOpened)
+	m.connsReused.Add(s.ConnsReused)
+	m.connsClosed.Add(s.ConnsClosed)
+	m.mergeSlowClients(s.SlowClients)
+	m.mergeStreams(s.Streams)
+	m.mergeDatasets(s.Datasets)
+
+	if m.series != nil {
+		shift := int(s.StartTime.Sub(m.startTime).Round(time.Second) / time.Second)
+
+		m.series.mu.Lock()
+		for i, b := range s.Series {
+			idx := i + shift
+			if idx < 0 {
+				continue
+			}
+			for len(m.series.buckets) <= idx {
+				m.series.buckets = append(m.series.buckets, bucket{})
+			}
+			dst := &m.series.buckets[idx]
+			dst.requests += b.Requests
+			dst.errors += b.Errors
+			dst.latencyCount += b.LatencyCount
+			dst.latencyNanos += b.LatencyNanos
+		}
+		if len(m.series.spikes) == 0 {
+			for _, w := range s.Spikes {
+				m.series.spikes = append(m.series.spikes, &spikeWindow{start: w.Start, end: w.End, peakRPS: w.PeakRPS, shape: w.Shape, finished: true})
+			}
+		} else {
+			// Each agent sent its share of the spike
+			for i := 0; i < len(s.Spikes) && i < len(m.series.spikes); i++ {
+				m.series.spikes[i].peakRPS += s.Spikes[i].PeakRPS
+			}
+		}
+		m.series.mu.Unlock()
+	}
+
+	if m.phases != nil {
+		m.phaseMu.Lock()
+		if len(m.phaseMarks) == 0 {
+			for _, mark := range s.PhaseMarks {
+				m.setPhaseAt(mark.Name, mark.At)
+			}
+		}
+		for name := range s.Phases {
+			if _, ok := m.phases[name]; !ok {
+				m.setPhaseAt(name, time.Time{})
+			}
+		}
+		phases := make(map[string]*Metrics, len(m.phases))
+		for name, child := range m.phases {
+			phases[name] = child
+		}
+		m.phaseMu.Unlock()
+
+		for name, ps := range s.Phases {
+			phases[name].merge(ps)
+		}
+	}
+}
+
+// merge adds the request, latency and endpoint state to the collector.
+func (m *Metrics) merge(s State) {
+	m.totalRequests.Add(s.TotalRequests)
+	m.successRequests.Add(s.SuccessRequests)
+	m.errorRequests.Add(s.ErrorRequests)
+	m.arrivals.Add(s.Arrivals)
+	m.queuedArrivals.Add(s.QueuedArrivals)
+	m.queueDelayNanos.Add(s.QueueDelayNanos)
+	if s.MaxQueueDelay > m.maxQueueDelay.Load() {
+		m.maxQueueDelay.Store(s.MaxQueueDelay)
+	}
+	m.iterations.Add(s.Iterations)
+	m.iterationNanos.Add(s.IterationNanos)
+
+	m.latenciesMu.Lock()
+	for idx, count := range s.Latencies {
+		l := histogramValue(idx)
+		for i := int64(0); i < count; i++ {
+			m.latencies = append(m.latencies, l)
+		}
+	}
+	m.latenciesMu.Unlock()
+
+	m.errorsMu.Lock()
+	for k, v := range s.ErrorsByStatus {
+		m.errorsByStatus[k] += v
+	}
+	m.errorsMu.Unlock()
+
+	for name, es := range s.Endpoints {
+		m.Endpoint(name).merge(es)
+	}
+}
+
+// histogramIndex returns the histogram bucket of a latency. Values below
+// 2^histogramSubBits nanoseconds are exact; larger values keep histogramSubBits
+// bits of precision.
+func histogramIndex(d time.Duration) int64 {
+	v := uint64(max(d, 0))
+	if v < 1<<histogramSubBits {
+		return int64(v)
+	}
+	shift := bits.Len64(v) - histogramSubBits - 1
+	return int64(shift+1)<<histogramSubBits | int64(v>>shift)&(1<<histogramSubBits-1)
+}
+
+// histogramValue returns the midpoint of a histogram bucket.
+func histogramValue(idx int64) time.Duration {
+	if idx < 1<<histogramSubBits {
+		return time.Duration(idx)
+	}
+	shift := idx>>histogramSubBits - 1
+	mantissa := idx&(1<<histogramSubBits-1) | 1<<histogramSubBits
+	return time.Duration(mantissa<<shift + (int64(1)<<shift)/2)
+}
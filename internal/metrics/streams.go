@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// StreamState holds the raw totals of the streamed responses of one endpoint.
+type StreamState struct {
+	Streams            int64
+	Incomplete         int64 // Streams that ended with an error before their end
+	Events             int64
+	Bytes              int64
+	FirstEvents        int64 // Streams that delivered at least one event
+	FirstEventNanos    int64 // Total time to the first event
+	MaxFirstEventNanos int64
+	DurationNanos      int64 // Total time the streams were open
+	MaxDurationNanos   int64
+}
+
+// StreamStats summarizes the streamed responses of one endpoint.
+type StreamStats struct {
+	Endpoint       string
+	Streams        int64 // Streamed responses read
+	Incomplete     int64 // Streams cut short by an error or the end of the test
+	Events         int64 // Events or lines received
+	Bytes          int64 // Body bytes received
+	MeanFirstEvent time.Duration
+	MaxFirstEvent  time.Duration
+	EventRate      float64 // Events per second of stream
+	MeanDuration   time.Duration
+	MaxDuration    time.Duration
+}
+
+// RecordStream records a streamed response of endpoint that delivered events in
+// bytes over duration, measured from the request. firstEvent is the time to the
+// first event, and is ignored if no event arrived.
+func (m *Metrics) RecordStream(endpoint string, events, bytes int64, firstEvent, duration time.Duration, complete bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+
+	s, ok := m.streams[endpoint]
+	if !ok {
+		s = &StreamState{}
+		m.streams[endpoint] = s
+	}
+	s.Streams++
+	if !complete {
+		s.Incomplete++
+	}
+	s.Events += events
+	s.Bytes += bytes
+	if events > 0 {
+		s.FirstEvents++
+		s.FirstEventNanos += int64(firstEvent)
+		s.MaxFirstEventNanos = max(s.MaxFirstEventNanos, int64(firstEvent))
+	}
+	s.DurationNanos += int64(duration)
+	s.MaxDurationNanos = max(s.MaxDurationNanos, int64(duration))
+}
+
+// streamStates returns a copy of the stream totals by endpoint.
+func (m *Metrics) streamStates() map[string]StreamState {
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+
+	if len(m.streams) == 0 {
+		return nil
+	}
+	states := make(map[string]StreamState, len(m.streams))
+	for endpoint, s := range m.streams {
+		states[endpoint] = *s
+	}
+	return states
+}
+
+// mergeStreams adds stream totals to the collector.
+func (m *Metrics) mergeStreams(states map[string]StreamState) {
+	m.streamsMu.Lock()
+	defer m.streamsMu.Unlock()
+
+	for endpoint, src := range states {
+		dst, ok := m.streams[endpoint]
+		if !ok {
+			dst = &StreamState{}
+			m.streams[endpoint] = dst
+		}
+		dst.Streams += src.Streams
+		dst.Incomplete += src.Incomplete
+		dst.Events += src.Events
+		dst.Bytes += src.Bytes
+		dst.FirstEvents += src.FirstEvents
+		dst.FirstEventNanos += src.FirstEventNanos
+		dst.MaxFirstEventNanos = max(dst.MaxFirstEventNanos, src.MaxFirstEventNanos)
+		dst.DurationNanos += src.DurationNanos
+		dst.MaxDurationNanos = max(dst.MaxDurationNanos, src.MaxDurationNanos)
+	}
+}
+
+// streamStats returns the statistics of every streaming endpoint, ordered by endpoint.
+func (m *Metrics) streamStats() []StreamStats {
+	states := m.streamStates()
+	if len(states) == 0 {
+		return nil
+	}
+
+	result := make([]StreamStats, 0, len(states))
+	for endpoint, s := range states {
+		stats := StreamStats{
+			Endpoint:      endpoint,
+			Streams:       s.Streams,
+			Incomplete:    s.Incomplete,
+			Events:        s.Events,
+			Bytes:         s.Bytes,
+			MaxFirstEvent: time.Duration(s.MaxFirstEventNanos),
+			MaxDuration:   time.Duration(s.MaxDurationNanos),
+		}
+		if s.FirstEvents > 0 {
+			stats.MeanFirstEvent = time.Duration(s.FirstEventNanos / s.FirstEvents)
+		}
+		if s.Streams > 0 {
+			stats.MeanDuration = time.Duration(s.DurationNanos / s.Streams)
+		}
+		if s.DurationNanos > 0 {
+			stats.EventRate = float64(s.Events) / time.Duration(s.DurationNanos).Seconds()
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Endpoint < result[j].Endpoint })
+	return result
+}
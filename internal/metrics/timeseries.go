@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Recovery thresholds: a second counts as recovered when its mean latency is within
+// recoveryLatencyFactor of the baseline (or recoveryLatencySlack above it, whichever is
+// larger) and its error rate is at most recoveryErrorSlack percentage points above the baseline.
+// A spike has recovered after recoverySeconds such seconds in a row.
+const (
+	recoveryLatencyFactor = 1.2
+	recoveryLatencySlack  = 500 * time.Microsecond
+	recoveryErrorSlack    = 1.0
+	recoverySeconds       = 3
+	baselineWindow        = 5 * time.Second
+)
+
+// Bucket aggregates the requests completed during one second of the test.
+type Bucket struct {
+	Offset      time.Duration // Start of the second relative to the start of the test
+	Requests    int64
+	Errors      int64
+	LatencyMean time.Duration
+}
+
+// SpikeResult describes the impact of a spike and how long the system took to recover.
+type SpikeResult struct {
+	Index            int
+	Offset           time.Duration
+	Duration         time.Duration
+	PeakRPS          int
+	Shape            string
+	HasBaseline      bool          // Whether requests completed in the baseline window before the spike
+	BaselineLatency  time.Duration // Mean latency before the spike
+	BaselineErrRate  float64
+	PeakLatency      time.Duration // Worst per-second mean latency during the spike
+	PeakErrRate      float64
+	Recovered        bool
+	RecoveryTime     time.Duration // Time from the end of the spike until latency and error rate returned to baseline
+	MeasuredRecovery time.Duration // How long after the spike recovery was observed for
+}
+
+// spikeWindow is a spike recorded by the runner.
+type spikeWindow struct {
+	start    time.Time
+	end      time.Time
+	peakRPS  int
+	shape    string
+	finished bool
+}
+
+// bucket is the internal per-second accumulator.
+type bucket struct {
+	requests     int64
+	errors       int64
+	latencyCount int64
+	latencyNanos int64
+}
+
+// timeSeries records per-second request counts, errors and latency.
+type timeSeries struct {
+	mu      sync.Mutex
+	buckets []bucket
+	spikes  []*spikeWindow
+}
+
+// record adds a request completed at now to the time series.
+func (ts *timeSeries) record(start, now time.Time, latency time.Duration, hasLatency, isError bool) {
+	idx := int(now.Sub(start) / time.Second)
+	if idx < 0 {
+		return
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for len(ts.buckets) <= idx {
+		ts.buckets = append(ts.buckets, bucket{})
+	}
+	b := &ts.buckets[idx]
+	b.requests++
+	if isError {
+		b.errors++
+	}
+	if hasLatency {
+		b.latencyCount++
+		b.latencyNanos += int64(latency)
+	}
+}
+
+// RecordSpikeStart records the start of a spike and returns a function that records its end.
+func (m *Metrics) RecordSpikeStart(peakRPS int, shape string) func() {
+	w := &spikeWindow{start: time.Now(), peakRPS: peakRPS, shape: shape}
+
+	m.mu.RLock()
+	series := m.series
+	m.mu.RUnlock()
+
+	series.mu.Lock()
+	series.spikes = append(series.spikes, w)
+	series.mu.Unlock()
+
+	return func() {
+		series.mu.Lock()
+		w.end = time.Now()
+		w.finished = true
+		series.mu.Unlock()
+	}
+}
+
+// timeline returns the per-second buckets up to now.
+func (m *Metrics) timeline(now time.Time) []Bucket {
+	m.series.mu.Lock()
+	defer m.series.mu.Unlock()
+
+	n := int(now.Sub(m.startTime) / time.Second)
+	if n > len(m.series.buckets) {
+		n = len(m.series.buckets)
+	}
+
+	result := make([]Bucket, 0, n)
+	for i := 0; i < n; i++ {
+		b := m.series.buckets[i]
+		out := Bucket{
+			Offset:   time.Duration(i) * time.Second,
+			Requests: b.requests,
+			Errors:   b.errors,
+		}
+		if b.latencyCount > 0 {
+			out.LatencyMean = time.Duration(b.latencyNanos / b.latencyCount)
+		}
+		result = append(result, out)
+	}
+	return result
+}
+
+// spikeResults evaluates every finished spike against the time series.
+func (m *Metrics) spikeResults(timeline []Bucket) []SpikeResult {
+	m.series.mu.Lock()
+	windows := make([]spikeWindow, 0, len(m.series.spikes))
+	for _, w := range m.series.spikes {
+		if w.finished {
+			windows = append(windows, *w)
+		}
+	}
+	m.series.mu.Unlock()
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	results := make([]SpikeResult, 0, len(windows))
+	for i, w := range windows {
+		start := w.start.Sub(m.startTime)
+		end := w.end.Sub(m.startTime)
+		r := SpikeResult{
+			Index:    i + 1,
+			Offset:   start,
+			Duration: end - start,
+			PeakRPS:  w.peakRPS,
+			Shape:    w.shape,
+		}
+
+		// Baseline: complete seconds before the spike, after the previous spike ended.
+		baselineFrom := start - baselineWindow
+		if i > 0 {
+			if prevEnd := windows[i-1].end.Sub(m.startTime); prevEnd > baselineFrom {
+				baselineFrom = prevEnd
+			}
+		}
+		var baselineRequests int64
+		r.BaselineLatency, r.BaselineErrRate, baselineRequests = aggregate(timeline, baselineFrom, start)
+		r.HasBaseline = baselineRequests > 0
+
+		for _, b := range timeline {
+			if b.Offset+time.Second <= start || b.Offset >= end {
+				continue
+			}
+			if b.LatencyMean > r.PeakLatency {
+				r.PeakLatency = b.LatencyMean
+			}
+			if rate := errorRate(b); rate > r.PeakErrRate {
+				r.PeakErrRate = rate
+			}
+		}
+
+		// Recovery is measured until the next spike starts or the time series ends.
+		limit := time.Duration(len(timeline)) * time.Second
+		if i+1 < len(windows) {
+			limit = windows[i+1].start.Sub(m.startTime)
+		}
+		if limit > end {
+			r.MeasuredRecovery = limit - end
+		}
+		if !r.HasBaseline {
+			results = append(results, r)
+			continue
+		}
+
+		latencyLimit := time.Duration(float64(r.BaselineLatency) * recoveryLatencyFactor)
+		if slack := r.BaselineLatency + recoveryLatencySlack; slack > latencyLimit {
+			latencyLimit = slack
+		}
+		// Only complete seconds starting after the spike ended count, and an idle
+		// second breaks the run since it shows nothing about the server.
+		first := end.Truncate(time.Second)
+		if first < end {
+			first += time.Second
+		}
+		good := 0
+		for _, b := range timeline {
+			if b.Offset < first || b.Offset+time.Second > limit {
+				continue
+			}
+			if b.Requests == 0 || b.LatencyMean > latencyLimit || errorRate(b) > r.BaselineErrRate+recoveryErrorSlack {
+				good = 0
+				continue
+			}
+			good++
+			if good == recoverySeconds {
+				r.Recovered = true
+				r.RecoveryTime = b.Offset - time.Duration(recoverySeconds-1)*time.Second - end
+				break
+			}
+		}
+
+		results = append(results, r)
+	}
+	return results
+}
+
+// aggregate returns the mean latency, error rate and request count of the complete
+// seconds in [from, to).
+func aggregate(timeline []Bucket, from, to time.Duration) (time.Duration, float64, int64) {
+	var requests, errors int64
+	var latencyNanos float64
+	for _, b := range timeline {
+		if b.Offset < from || b.Offset+time.Second > to {
+			continue
+		}
+		requests += b.Requests
+		errors += b.Errors
+		latencyNanos += float64(b.LatencyMean) * float64(b.Requests)
+	}
+	if requests == 0 {
+		return 0, 0, 0
+	}
+	return time.Duration(latencyNanos / float64(requests)), float64(errors) / float64(requests) * 100, requests
+}
+
+// errorRate returns the error rate of a bucket as a percentage.
+func errorRate(b Bucket) float64 {
+	if b.Requests == 0 {
+		return 0
+	}
+	return float64(b.Errors) / float64(b.Requests) * 100
+}
@@ -0,0 +1,49 @@
+package metrics
+
+// maxVerificationExamples bounds the violations described in the report.
+const maxVerificationExamples = 10
+
+// Verification is the result of checking the server's final dataset against the
+// writes it acknowledged during the run.
+type Verification struct {
+	Creates        int64 // Acknowledged creates
+	Updates        int64 // Acknowledged updates
+	Deletes        int64 // Acknowledged deletes
+	Unacknowledged int64 // Writes with an unknown outcome, which may or may not have been applied
+	Items          int   // Items in the final dataset
+
+	Missing       int // Items missing although nothing deleted them
+	LostDeletes   int // Items present after an acknowledged delete
+	LostUpdates   int // Items holding a value older than an acknowledged write
+	UnknownValues int // Items holding a value no write sent
+	Unrecorded    int // Items present without a recorded create
+
+	Examples []string `json:",omitempty"` // Descriptions of the first violations
+	Error    string   `json:",omitempty"` // Why the verification couldn't run
+}
+
+// Violations returns the number of items contradicting the acknowledged writes.
+func (v *Verification) Violations() int {
+	return v.Missing + v.LostDeletes + v.LostUpdates + v.UnknownValues + v.Unrecorded
+}
+
+// AddExample describes a violation, up to a limit.
+func (v *Verification) AddExample(example string) {
+	if len(v.Examples) < maxVerificationExamples {
+		v.Examples = append(v.Examples, example)
+	}
+}
+
+// SetVerification records the result of the post-run verification.
+func (m *Metrics) SetVerification(v Verification) {
+	m.verificationMu.Lock()
+	defer m.verificationMu.Unlock()
+	m.verification = &v
+}
+
+// verificationResult returns the result of the post-run verification, if any.
+func (m *Metrics) verificationResult() *Verification {
+	m.verificationMu.Lock()
+	defer m.verificationMu.Unlock()
+	return m.verification
+}
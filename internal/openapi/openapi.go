@@ -0,0 +1,503 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kolosys/helix-stress-test/internal/scenario"
+)
+
+// Document is the subset of an OpenAPI 3 document needed to generate endpoints.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Server is an entry of the document's servers list.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds reusable definitions referenced with $ref.
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas"`
+	Parameters    map[string]*Parameter   `json:"parameters"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies"`
+}
+
+// PathItem describes the operations available on a path.
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Post       *Operation   `json:"post"`
+	Put        *Operation   `json:"put"`
+	Patch      *Operation   `json:"patch"`
+	Delete     *Operation   `json:"delete"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter describes a path, query or header parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+	Example  any     `json:"example"`
+}
+
+// RequestBody describes the body of an operation.
+type RequestBody struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType describes the body for a content type.
+type MediaType struct {
+	Schema  *Schema `json:"schema"`
+	Example any     `json:"example"`
+}
+
+// Schema is the subset of a JSON schema used to generate values.
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       any                `json:"type"` // string, or list of strings in OpenAPI 3.1
+	Format     string             `json:"format"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Example    any                `json:"example"`
+	Examples   []any              `json:"examples"`
+	Default    any                `json:"default"`
+	Enum       []any              `json:"enum"`
+	AllOf      []*Schema          `json:"allOf"`
+	OneOf      []*Schema          `json:"oneOf"`
+	AnyOf      []*Schema          `json:"anyOf"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+}
+
+// methods lists the generated operations in output order.
+var methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// maxSchemaDepth bounds the nesting of generated values.
+const maxSchemaDepth = 8
+
+// Load reads an OpenAPI 3 document in JSON or YAML format.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+	}
+
+	doc, err := Parse(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Parse parses an OpenAPI 3 document. ext is the file extension used to pick the format;
+// documents starting with '{' are always treated as JSON.
+func Parse(data []byte, ext string) (*Document, error) {
+	if ext != ".json" && !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		value, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("failed to convert YAML document: %w", err)
+		}
+	}
+
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q (must be 3.x)", doc.OpenAPI)
+	}
+	if len(doc.Paths) == 0 {
+		return nil, fmt.Errorf("document contains no paths")
+	}
+	return &doc, nil
+}
+
+// Generate converts every operation of the document into a scenario endpoint.
+//
+// Path parameters become placeholders understood by the runner: integer parameters named
+// like an ID use {id}, or {delete_id} in DELETE operations, other integers {int:MIN-MAX}, UUIDs {uuid} and strings {string:N},
+// unless the parameter has an example or enum value. Required query parameters are
+// appended with example or generated values, and JSON request bodies are built from
+// the media type example or the schema.
+func (d *Document) Generate(source string) *scenario.Scenario {
+	prefix := d.basePath()
+
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	s := &scenario.Scenario{Source: source}
+	for _, path := range paths {
+		item := d.Paths[path]
+		for _, method := range methods {
+			op := item.operation(method)
+			if op == nil {
+				continue
+			}
+
+			params := d.mergeParameters(item.Parameters, op.Parameters)
+			ep := scenario.Endpoint{
+				Name:   method + " " + prefix + path,
+				Method: method,
+				Path:   prefix + d.buildPath(method, path, params),
+			}
+
+			if body, contentType, ok := d.buildBody(op.RequestBody); ok {
+				ep.Body = body
+				ep.Headers = map[string]string{"Content-Type": contentType}
+			}
+
+			s.Endpoints = append(s.Endpoints, ep)
+		}
+	}
+	return s
+}
+
+// operation returns the operation for the given method, or nil.
+func (p PathItem) operation(method string) *Operation {
+	switch method {
+	case "GET":
+		return p.Get
+	case "POST":
+		return p.Post
+	case "PUT":
+		return p.Put
+	case "PATCH":
+		return p.Patch
+	case "DELETE":
+		return p.Delete
+	}
+	return nil
+}
+
+// basePath returns the path component of the first server URL, without a trailing slash.
+func (d *Document) basePath() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(d.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// mergeParameters resolves references and lets operation parameters override path-level ones.
+func (d *Document) mergeParameters(pathParams, opParams []*Parameter) []*Parameter {
+	var result []*Parameter
+	index := make(map[string]int)
+	for _, list := range [][]*Parameter{pathParams, opParams} {
+		for _, param := range list {
+			param = d.resolveParameter(param)
+			if param == nil {
+				continue
+			}
+			key := param.In + ":" + param.Name
+			if i, ok := index[key]; ok {
+				result[i] = param
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, param)
+		}
+	}
+	return result
+}
+
+// buildPath substitutes path parameters and appends required query parameters.
+func (d *Document) buildPath(method, path string, params []*Parameter) string {
+	query := url.Values{}
+	for _, param := range params {
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", d.pathValue(method, param))
+		case "query":
+			if param.Required {
+				query.Set(param.Name, fmt.Sprint(d.exampleValue(param.Schema, param.Example, 0)))
+			}
+		}
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// pathValue returns the placeholder or literal used for a path parameter. ID
+// parameters of DELETE operations use the delete range, so deletes don't remove
+// the items other operations read and update.
+func (d *Document) pathValue(method string, param *Parameter) string {
+	schema := d.resolveSchema(param.Schema, 0)
+	if param.Example != nil {
+		return url.PathEscape(fmt.Sprint(param.Example))
+	}
+	if schema == nil {
+		return "{string:8}"
+	}
+	if v := schemaExample(schema); v != nil {
+		return url.PathEscape(fmt.Sprint(v))
+	}
+
+	switch schemaType(schema) {
+	case "integer", "number":
+		if strings.HasSuffix(strings.ToLower(param.Name), "id") && schema.Minimum == nil && schema.Maximum == nil {
+			if method == "DELETE" {
+				return "{delete_id}"
+			}
+			return "{id}"
+		}
+		min, max := 1, 1000
+		if schema.Minimum != nil {
+			min = int(*schema.Minimum)
+		}
+		if schema.Maximum != nil {
+			max = int(*schema.Maximum)
+		}
+		if max < min {
+			max = min
+		}
+		return fmt.Sprintf("{int:%d-%d}", min, max)
+	case "string":
+		if schema.Format == "uuid" {
+			return "{uuid}"
+		}
+		return fmt.Sprintf("{string:%d}", stringLength(schema))
+	}
+	return "{string:8}"
+}
+
+// buildBody generates a body for the request, preferring application/json, then
+// the first other JSON content type in sorted order.
+func (d *Document) buildBody(body *RequestBody) (string, string, bool) {
+	body = d.resolveRequestBody(body)
+	if body == nil || len(body.Content) == 0 {
+		return "", "", false
+	}
+
+	contentType := ""
+	if _, ok := body.Content["application/json"]; ok {
+		contentType = "application/json"
+	} else {
+		types := make([]string, 0, len(body.Content))
+		for ct := range body.Content {
+			types = append(types, ct)
+		}
+		sort.Strings(types)
+		for _, ct := range types {
+			if strings.HasSuffix(ct, "+json") {
+				contentType = ct
+				break
+			}
+		}
+	}
+	if contentType == "" {
+		return "", "", false
+	}
+
+	media := body.Content[contentType]
+	value := d.exampleValue(media.Schema, media.Example, 0)
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", "", false
+	}
+	return string(data), contentType, true
+}
+
+// exampleValue returns the explicit example if set, otherwise a value generated from the schema.
+func (d *Document) exampleValue(schema *Schema, example any, depth int) any {
+	if example != nil {
+		return example
+	}
+	return d.generate(schema, depth)
+}
+
+// generate builds an example value from a schema.
+// Self-referencing schemas are expanded once; nested occurrences generate null.
+func (d *Document) generate(schema *Schema, depth int) any {
+	return d.generateSchema(schema, depth, make(map[string]bool))
+}
+
+func (d *Document) generateSchema(schema *Schema, depth int, visiting map[string]bool) any {
+	if schema != nil && schema.Ref != "" {
+		if visiting[schema.Ref] {
+			return nil
+		}
+		visiting[schema.Ref] = true
+		defer delete(visiting, schema.Ref)
+	}
+
+	schema = d.resolveSchema(schema, depth)
+	if schema == nil || depth > maxSchemaDepth {
+		return nil
+	}
+	if v := schemaExample(schema); v != nil {
+		return v
+	}
+
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]any)
+		for _, part := range schema.AllOf {
+			if obj, ok := d.generateSchema(part, depth+1, visiting).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return d.generateSchema(schema.OneOf[0], depth+1, visiting)
+	}
+	if len(schema.AnyOf) > 0 {
+		return d.generateSchema(schema.AnyOf[0], depth+1, visiting)
+	}
+
+	switch schemaType(schema) {
+	case "object", "":
+		obj := make(map[string]any, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			obj[name] = d.generateSchema(prop, depth+1, visiting)
+		}
+		return obj
+	case "array":
+		return []any{d.generateSchema(schema.Items, depth+1, visiting)}
+	case "integer":
+		if schema.Minimum != nil {
+			return int64(*schema.Minimum)
+		}
+		return 1
+	case "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "test@example.com"
+		case "uuid":
+			return "00000000-0000-4000-8000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return strings.Repeat("x", stringLength(schema))
+	}
+	return nil
+}
+
+// resolveSchema follows $ref pointers to component schemas.
+func (d *Document) resolveSchema(schema *Schema, depth int) *Schema {
+	for schema != nil && schema.Ref != "" {
+		if depth > maxSchemaDepth {
+			return nil
+		}
+		schema = d.Components.Schemas[refName(schema.Ref, "schemas")]
+		depth++
+	}
+	return schema
+}
+
+// resolveParameter follows a $ref pointer to a component parameter.
+func (d *Document) resolveParameter(param *Parameter) *Parameter {
+	if param != nil && param.Ref != "" {
+		return d.Components.Parameters[refName(param.Ref, "parameters")]
+	}
+	return param
+}
+
+// resolveRequestBody follows a $ref pointer to a component request body.
+func (d *Document) resolveRequestBody(body *RequestBody) *RequestBody {
+	if body != nil && body.Ref != "" {
+		return d.Components.RequestBodies[refName(body.Ref, "requestBodies")]
+	}
+	return body
+}
+
+// refName extracts the component name from a local reference such as "#/components/schemas/Item".
+func refName(ref, kind string) string {
+	return strings.TrimPrefix(ref, "#/components/"+kind+"/")
+}
+
+// schemaType returns the schema type, taking the first non-null entry of a 3.1 type list.
+func schemaType(schema *Schema) string {
+	switch t := schema.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if len(schema.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// schemaExample returns the example, default or first enum value of a schema.
+func schemaExample(schema *Schema) any {
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case len(schema.Examples) > 0:
+		return schema.Examples[0]
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+	return nil
+}
+
+// stringLength returns a generated string length that satisfies the schema bounds.
+func stringLength(schema *Schema) int {
+	length := 8
+	if schema.MinLength != nil && *schema.MinLength > length {
+		length = *schema.MinLength
+	}
+	if schema.MaxLength != nil && *schema.MaxLength < length {
+		length = *schema.MaxLength
+	}
+	if length <= 0 {
+		length = 1
+	}
+	return length
+}
@@ -0,0 +1,60 @@
+package openapi
+
+import "testing"
+
+const petsDocument = `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/pets/{petId}": {
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "integer"}}],
+      "get": {},
+      "put": {
+        "requestBody": {
+          "content": {
+            "application/vnd.pet+json": {"example": {"name": "vendor"}},
+            "application/merge-patch+json": {"example": {"name": "patch"}},
+            "application/json": {"example": {"name": "json"}}
+          }
+        }
+      },
+      "patch": {
+        "requestBody": {
+          "content": {
+            "application/vnd.pet+json": {"example": {"name": "vendor"}},
+            "application/merge-patch+json": {"example": {"name": "patch"}}
+          }
+        }
+      },
+      "delete": {}
+    }
+  }
+}`
+
+func TestGenerate(t *testing.T) {
+	doc, err := Parse([]byte(petsDocument), ".json")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	type endpoint struct{ path, body, contentType string }
+	want := map[string]endpoint{
+		"GET":    {path: "/pets/{id}"},
+		"PUT":    {path: "/pets/{id}", body: `{"name":"json"}`, contentType: "application/json"},
+		"PATCH":  {path: "/pets/{id}", body: `{"name":"patch"}`, contentType: "application/merge-patch+json"},
+		"DELETE": {path: "/pets/{delete_id}"},
+	}
+
+	// Map iteration must not change the generated endpoints
+	for range 20 {
+		s := doc.Generate("pets.json")
+		if len(s.Endpoints) != len(want) {
+			t.Fatalf("Generate() returned %d endpoints, want %d", len(s.Endpoints), len(want))
+		}
+		for _, ep := range s.Endpoints {
+			got := endpoint{path: ep.Path, body: ep.Body, contentType: ep.Headers["Content-Type"]}
+			if got != want[ep.Method] {
+				t.Fatalf("Generate() %s = %+v, want %+v", ep.Method, got, want[ep.Method])
+			}
+		}
+	}
+}
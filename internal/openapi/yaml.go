@@ -0,0 +1,600 @@
+package openapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by OpenAPI documents into generic values
+// (map[string]any, []any, string, bool, int64, float64 and nil).
+//
+// Supported: block mappings and sequences, flow collections, plain and quoted scalars
+// (which may span several lines), literal (|) and folded (>) block scalars, and
+// comments. Anchors, aliases, tags and multi-document streams are not supported, and
+// tabs are rejected in indentation, as YAML requires.
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	for i, line := range p.lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", i+1)
+		}
+	}
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	text := strings.TrimSpace(p.lines[p.pos])
+	if text == "---" {
+		p.next()
+		p.skipBlank()
+	}
+
+	value, err := p.parseNode(p.indent())
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) && strings.TrimSpace(p.lines[p.pos]) != "..." {
+		return nil, p.errorf("unexpected content")
+	}
+	return value, nil
+}
+
+// yamlParser is a line-oriented recursive descent parser.
+type yamlParser struct {
+	lines []string
+	pos   int
+	col   int // Column where the current line's node starts, after a sequence dash (0 for its indentation)
+}
+
+// next advances to the next line.
+func (p *yamlParser) next() {
+	p.pos++
+	p.col = 0
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml: line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// skipBlank advances past empty and comment-only lines.
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) {
+		text := strings.TrimSpace(p.lines[p.pos][p.col:])
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		p.next()
+	}
+}
+
+// indent returns the indentation of the current line, or the column of the node
+// following a sequence dash on it.
+func (p *yamlParser) indent() int {
+	if p.col > 0 {
+		return p.col
+	}
+	return lineIndent(p.lines[p.pos])
+}
+
+// text returns the current line from its indentation or node column, without
+// trailing comment.
+func (p *yamlParser) text() string {
+	return stripComment(strings.TrimLeft(p.lines[p.pos][p.col:], " "))
+}
+
+// atDocumentMarker reports whether the current line ends the document (... or ---).
+func (p *yamlParser) atDocumentMarker() bool {
+	text := p.lines[p.pos]
+	return text == "..." || text == "---"
+}
+
+// lineIndent returns the number of leading spaces of line.
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// parseNode parses the block node starting at the current line with the given indentation.
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	if isSequenceItem(p.text()) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitMappingEntry(p.text()); ok {
+		return p.parseMapping(indent)
+	}
+
+	return p.parseValue(indent, p.text())
+}
+
+// parseMapping parses a block mapping whose keys are at the given indentation.
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	result := make(map[string]any)
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.indent() != indent || isSequenceItem(p.text()) || p.atDocumentMarker() {
+			return result, nil
+		}
+
+		key, rest, ok := splitMappingEntry(p.text())
+		if !ok {
+			return nil, p.errorf("expected mapping entry")
+		}
+		if strings.HasPrefix(key, "&") || strings.HasPrefix(rest, "&") || strings.HasPrefix(rest, "*") {
+			return nil, p.errorf("anchors and aliases are not supported")
+		}
+
+		value, err := p.parseValue(indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+}
+
+// parseSequence parses a block sequence whose dashes are at the given indentation.
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	result := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.indent() != indent || !isSequenceItem(p.text()) {
+			return result, nil
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(p.text(), "-"))
+		if rest == "" {
+			value, err := p.parseValue(indent, "")
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		if _, _, ok := splitMappingEntry(rest); ok || isSequenceItem(rest) {
+			// Treat "- key: value" as a mapping indented to the position of its first key.
+			line := p.lines[p.pos]
+			start := p.indent() + 1
+			p.col = start + lineIndent(line[start:])
+			value, err := p.parseNode(p.col)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		value, err := p.parseValue(indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+}
+
+// parseValue parses the value of a mapping entry or sequence item.
+// rest is the text after the key or dash on the current line.
+func (p *yamlParser) parseValue(indent int, rest string) (any, error) {
+	switch {
+	case rest == "":
+		p.next()
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return nil, nil
+		}
+		child := p.indent()
+		if child > indent || (child == indent && isSequenceItem(p.text())) {
+			return p.parseNode(child)
+		}
+		return nil, nil
+
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return p.parseBlockScalar(indent, rest), nil
+
+	case strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "{"):
+		// Flow collections may span several lines; join them until brackets balance.
+		text := rest
+		for !flowBalanced(text) {
+			p.next()
+			if p.pos >= len(p.lines) {
+				return nil, p.errorf("unterminated flow collection")
+			}
+			text += " " + p.text()
+		}
+		p.next()
+		return parseFlow(text)
+
+	default:
+		text, err := p.parseMultiline(indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		return parseScalar(text)
+	}
+}
+
+// parseMultiline returns the text of a plain or quoted scalar starting with first
+// on the current line, joined with its continuation lines. A plain scalar goes on
+// over the following lines indented deeper than its parent node, up to a comment,
+// and a quoted one up to its closing quote. Line breaks fold into spaces, and blank
+// lines into newlines.
+func (p *yamlParser) parseMultiline(indent int, first string) (string, error) {
+	quote := first[0]
+	quoted := quote == '"' || quote == '\''
+	newline := "\n"
+	if quote == '"' {
+		// Escaped, since the text is unquoted as a Go string
+		newline = `\n`
+	}
+
+	text := first
+	start := p.pos
+	raw := strings.TrimSpace(p.lines[p.pos][p.col:])
+	p.next()
+	if !quoted && stripComment(raw) != raw {
+		// A comment ends the scalar
+		return text, nil
+	}
+
+	blanks := 0
+	for p.pos < len(p.lines) && !(quoted && quoteClosed(text)) {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			blanks++
+			p.next()
+			continue
+		case !quoted && (lineIndent(line) <= indent || strings.HasPrefix(trimmed, "#")):
+			return text, nil
+		}
+
+		content := trimmed
+		if !quoted {
+			content = stripComment(trimmed)
+			if _, _, ok := splitMappingEntry(content); ok {
+				return "", p.errorf("mapping values are not allowed in a multi-line scalar")
+			}
+		}
+		if blanks > 0 {
+			text += strings.Repeat(newline, blanks)
+		} else {
+			text += " "
+		}
+		text += content
+		blanks = 0
+		p.next()
+		if content != trimmed {
+			// A comment ends the scalar
+			return text, nil
+		}
+	}
+
+	if quoted {
+		if !quoteClosed(text) {
+			return "", fmt.Errorf("yaml: line %d: unterminated quoted string", start+1)
+		}
+		text = stripComment(text)
+	}
+	return text, nil
+}
+
+// quoteClosed reports whether the quoted scalar starting text is closed.
+func quoteClosed(text string) bool {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] != quote:
+		case quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar.
+func (p *yamlParser) parseBlockScalar(indent int, header string) string {
+	folded := strings.HasPrefix(header, ">")
+	chomp := strings.TrimLeft(header[1:], "0123456789")
+	p.next()
+
+	var lines []string
+	blockIndent := -1
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" {
+			if lineIndent(line) <= indent {
+				break
+			}
+			if blockIndent < 0 {
+				blockIndent = lineIndent(line)
+			}
+			if lineIndent(line) < blockIndent {
+				break
+			}
+			lines = append(lines, line[blockIndent:])
+		} else {
+			lines = append(lines, "")
+		}
+		p.next()
+	}
+
+	// Trailing blank lines belong to the document, not the scalar.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var text string
+	if folded {
+		var b strings.Builder
+		for i, line := range lines {
+			switch {
+			case i == 0:
+			case line == "" || lines[i-1] == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+
+	if chomp != "-" && text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+// isSequenceItem reports whether text starts a block sequence item.
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitMappingEntry splits "key: value" into key and value, ignoring colons inside quotes and flow collections.
+func splitMappingEntry(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' ' || text[i+1] == '\t'):
+			key := strings.TrimSpace(text[:i])
+			if unquoted, err := parseScalar(key); err == nil && key != "" {
+				key = fmt.Sprint(unquoted)
+				if unquoted == nil {
+					key = "null"
+				}
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripComment removes a trailing comment that is not inside a quoted string.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '[' || text[i-1] == '{' || text[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return strings.TrimRight(text, " \t")
+}
+
+// flowBalanced reports whether all brackets outside quotes are closed.
+func flowBalanced(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// parseFlow parses a flow collection such as [a, b] or {a: 1, b: [c]}.
+func parseFlow(text string) (any, error) {
+	f := &flowParser{text: text}
+	value, err := f.parse()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.pos < len(f.text) {
+		return nil, fmt.Errorf("yaml: unexpected %q after flow collection", f.text[f.pos:])
+	}
+	return value, nil
+}
+
+// flowParser parses flow collections character by character.
+type flowParser struct {
+	text string
+	pos  int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *flowParser) parse() (any, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("yaml: unexpected end of flow collection")
+	}
+
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		result := []any{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == ']' {
+				f.pos++
+				return result, nil
+			}
+			value, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+
+	case '{':
+		f.pos++
+		result := make(map[string]any)
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == '}' {
+				f.pos++
+				return result, nil
+			}
+			key, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			f.skipSpace()
+			if f.pos >= len(f.text) || f.text[f.pos] != ':' {
+				return nil, fmt.Errorf("yaml: expected ':' in flow mapping")
+			}
+			f.pos++
+			value, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = value
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+
+	case '"', '\'':
+		quote := f.text[f.pos]
+		end := f.pos + 1
+		for end < len(f.text) {
+			if f.text[end] == quote {
+				if quote == '\'' && end+1 < len(f.text) && f.text[end+1] == '\'' {
+					end += 2
+					continue
+				}
+				break
+			}
+			if quote == '"' && f.text[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(f.text) {
+			return nil, fmt.Errorf("yaml: unterminated quoted string")
+		}
+		token := f.text[f.pos : end+1]
+		f.pos = end + 1
+		return parseScalar(token)
+
+	default:
+		start := f.pos
+		for f.pos < len(f.text) {
+			c := f.text[f.pos]
+			if c == ',' || c == ']' || c == '}' || (c == ':' && (f.pos+1 == len(f.text) || f.text[f.pos+1] == ' ')) {
+				break
+			}
+			f.pos++
+		}
+		return parseScalar(strings.TrimSpace(f.text[start:f.pos]))
+	}
+}
+
+// separator consumes a comma or detects the closing bracket.
+func (f *flowParser) separator(closing byte) error {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return fmt.Errorf("yaml: unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	default:
+		return fmt.Errorf("yaml: unexpected %q in flow collection", f.text[f.pos])
+	}
+}
+
+// parseScalar converts a plain or quoted scalar into a typed value.
+func parseScalar(text string) (any, error) {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: invalid double-quoted string %s", text)
+		}
+		return value, nil
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "xXnN") {
+		return f, nil
+	}
+	return text, nil
+}
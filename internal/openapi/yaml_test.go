@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{
+			name: "nested mappings and scalars",
+			in: `openapi: 3.0.0
+info:
+  title: Pets # the title
+  version: "1.0"
+  count: 3
+  ratio: 0.5
+  enabled: true
+  missing: ~
+`,
+			want: map[string]any{
+				"openapi": "3.0.0",
+				"info": map[string]any{
+					"title":   "Pets",
+					"version": "1.0",
+					"count":   int64(3),
+					"ratio":   0.5,
+					"enabled": true,
+					"missing": nil,
+				},
+			},
+		},
+		{
+			name: "sequences of mappings",
+			in: `parameters:
+  - name: id
+    in: path
+  -   name: limit
+      in: query
+tags:
+- a
+- b
+`,
+			want: map[string]any{
+				"parameters": []any{
+					map[string]any{"name": "id", "in": "path"},
+					map[string]any{"name": "limit", "in": "query"},
+				},
+				"tags": []any{"a", "b"},
+			},
+		},
+		{
+			name: "nested sequences",
+			in: `matrix:
+  - - 1
+    - 2
+  - - 3
+`,
+			want: map[string]any{
+				"matrix": []any{[]any{int64(1), int64(2)}, []any{int64(3)}},
+			},
+		},
+		{
+			name: "flow collections",
+			in: `required: [id, name]
+example: {id: 1, tags: [a, "b, c"]}
+`,
+			want: map[string]any{
+				"required": []any{"id", "name"},
+				"example":  map[string]any{"id": int64(1), "tags": []any{"a", "b, c"}},
+			},
+		},
+		{
+			name: "block scalars",
+			in: `literal: |
+  line one
+  line two
+folded: >-
+  folded
+  text
+`,
+			want: map[string]any{
+				"literal": "line one\nline two\n",
+				"folded":  "folded text",
+			},
+		},
+		{
+			name: "multi-line plain scalar",
+			in: `info:
+  description: A long description
+    wrapped over
+    several lines.
+
+    With a second paragraph.
+  title: Pets
+`,
+			want: map[string]any{
+				"info": map[string]any{
+					"description": "A long description wrapped over several lines.\nWith a second paragraph.",
+					"title":       "Pets",
+				},
+			},
+		},
+		{
+			name: "multi-line plain scalar in a sequence",
+			in: `- first item
+  continued # comment
+- second
+`,
+			want: []any{"first item continued", "second"},
+		},
+		{
+			name: "multi-line quoted scalars",
+			in: `double: "a double-quoted
+  string"
+single: 'it''s
+  single'
+`,
+			want: map[string]any{
+				"double": "a double-quoted string",
+				"single": "it's single",
+			},
+		},
+		{
+			name: "document markers",
+			in: `---
+a: 1
+...
+`,
+			want: map[string]any{"a": int64(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("parseYAML() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "tab indentation",
+			in:   "paths:\n\t/pets:\n\t\tget: {}\n",
+			want: "yaml: line 2: tabs are not allowed in indentation",
+		},
+		{
+			name: "mapping inside a plain scalar",
+			in:   "a: value\n  b: 1\n",
+			want: "yaml: line 2: mapping values are not allowed in a multi-line scalar",
+		},
+		{
+			name: "unterminated quoted string",
+			in:   "a: \"open\n  still open\n",
+			want: "yaml: line 1: unterminated quoted string",
+		},
+		{
+			name: "unterminated flow collection",
+			in:   "a: [1, 2\n",
+			want: "unterminated flow collection",
+		},
+		{
+			name: "alias",
+			in:   "a: *ref\n",
+			want: "anchors and aliases are not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseYAML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLKeepsLines(t *testing.T) {
+	in := "items:\n  - name: a\n    value: 1\n"
+	p := &yamlParser{lines: strings.Split(in, "\n")}
+	before := append([]string(nil), p.lines...)
+
+	if _, err := p.parseNode(p.indent()); err != nil {
+		t.Fatalf("parseNode() error = %v", err)
+	}
+	if !reflect.DeepEqual(p.lines, before) {
+		t.Errorf("parseNode() changed the lines to %q, want %q", p.lines, before)
+	}
+}
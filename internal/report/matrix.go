@@ -0,0 +1,235 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/matrix"
+)
+
+// MatrixReport is the JSON report of a matrix test.
+type MatrixReport struct {
+	Runs    []matrix.Result
+	Effects []matrix.Effect
+}
+
+// CompareReport is the JSON report of a compare test.
+type CompareReport struct {
+	Runs     []matrix.Result
+	Overhead []matrix.Overhead
+}
+
+// GenerateMatrix generates and writes the report of a matrix test.
+func (g *Generator) GenerateMatrix(results []matrix.Result) error {
+	writer, closeWriter, err := g.output()
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+
+	effects := matrix.MiddlewareEffects(g.cfg.MatrixMiddleware, results)
+
+	switch g.cfg.ReportFormat {
+	case "json":
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(MatrixReport{Runs: results, Effects: effects})
+	case "text":
+		return g.generateMatrixText(writer, results, effects)
+	default:
+		return fmt.Errorf("unknown report format: %s", g.cfg.ReportFormat)
+	}
+}
+
+// generateMatrixText generates a human-readable matrix report.
+func (g *Generator) generateMatrixText(w io.Writer, results []matrix.Result, effects []matrix.Effect) error {
+	var b strings.Builder
+
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n")
+	b.WriteString("HELIX STRESS TEST REPORT - MIDDLEWARE MATRIX\n")
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n\n")
+
+	g.writeRunsConfig(&b, len(results))
+
+	// Runs
+	b.WriteString("Middleware Matrix:\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	writeRuns(&b, "Middleware", results)
+
+	// Effects
+	if len(results) > 1 {
+		b.WriteString("Middleware Overhead (average change when enabled):\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-12s %9s %11s %11s %11s %11s\n", "Middleware", "RPS", "Mean", "P95", "P99", "Errors"))
+		for _, e := range effects {
+			b.WriteString(fmt.Sprintf("  %-12s %+8.2f%% %11s %11s %11s %+9.2fpp\n",
+				e.Middleware,
+				e.RPSChange,
+				formatDelta(e.MeanDelta),
+				formatDelta(e.P95Delta),
+				formatDelta(e.P99Delta),
+				e.ErrorRateDelta,
+			))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n")
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// formatDelta formats a duration difference with its sign.
+func formatDelta(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	return "+" + formatDuration(d)
+}
+
+// joinOrNone joins names with commas, or returns "none" if there are none.
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// GenerateCompare generates and writes the report of a compare test. The first
+// result is the net/http baseline and the second the Helix server.
+func (g *Generator) GenerateCompare(results []matrix.Result) error {
+	writer, closeWriter, err := g.output()
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+
+	var overheads []matrix.Overhead
+	if len(results) == 2 {
+		overheads = matrix.EndpointOverhead(results[0], results[1])
+	}
+
+	switch g.cfg.ReportFormat {
+	case "json":
+		enc := json.NewEncoder(writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(CompareReport{Runs: results, Overhead: overheads})
+	case "text":
+		return g.generateCompareText(writer, results, overheads)
+	default:
+		return fmt.Errorf("unknown report format: %s", g.cfg.ReportFormat)
+	}
+}
+
+// generateCompareText generates a human-readable comparison report.
+func (g *Generator) generateCompareText(w io.Writer, results []matrix.Result, overheads []matrix.Overhead) error {
+	var b strings.Builder
+
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n")
+	b.WriteString("HELIX STRESS TEST REPORT - HELIX VS NET/HTTP\n")
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n\n")
+
+	g.writeRunsConfig(&b, len(results))
+
+	// Runs
+	b.WriteString("Servers:\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	writeRuns(&b, "Server", results)
+
+	// Overhead
+	if len(overheads) > 0 {
+		b.WriteString("Helix Overhead vs net/http:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-26s %9s %9s %8s %9s %9s %8s\n", "Endpoint", "Base Mean", "Mean", "Change", "Base P99", "P99", "Change"))
+		for _, o := range overheads {
+			b.WriteString(fmt.Sprintf("  %-26s %9s %9s %+7.1f%% %9s %9s %+7.1f%%\n",
+				truncate(o.Name, 26),
+				formatDuration(o.BaselineMean),
+				formatDuration(o.Mean),
+				o.MeanChange,
+				formatDuration(o.BaselineP99),
+				formatDuration(o.P99),
+				o.P99Change,
+			))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("=" + strings.Repeat("=", 78) + "\n")
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// writeRunsConfig writes the configuration section of a report covering several runs.
+func (g *Generator) writeRunsConfig(b *strings.Builder, variants int) {
+	b.WriteString("Test Configuration:\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	b.WriteString(fmt.Sprintf("  Test Type:     %s\n", g.cfg.TestType))
+	b.WriteString(fmt.Sprintf("  Server Addr:   %s\n", g.cfg.ServerAddr))
+	b.WriteString(fmt.Sprintf("  Store:         %s\n", g.cfg.Store))
+	if g.cfg.TestType == config.TestTypeMatrix {
+		b.WriteString(fmt.Sprintf("  Middleware:    %s (toggled: %s)\n", joinOrNone(g.cfg.Middleware), strings.Join(g.cfg.MatrixMiddleware, ", ")))
+	} else {
+		b.WriteString(fmt.Sprintf("  Middleware:    %s (helix only)\n", joinOrNone(g.cfg.Middleware)))
+	}
+	b.WriteString(fmt.Sprintf("  Runs:          %d variants x %d runs of %s", variants, g.cfg.MatrixRepeats, g.cfg.Duration))
+	if g.cfg.Warmup > 0 {
+		b.WriteString(fmt.Sprintf(" (+%s warm-up)", g.cfg.Warmup))
+	}
+	b.WriteString(", in random order per round\n")
+	b.WriteString(fmt.Sprintf("  Concurrent:    %d\n", g.cfg.Concurrent))
+	if g.cfg.Executor == config.ExecutorVU {
+		b.WriteString(fmt.Sprintf("  Executor:      %s (%d virtual users)\n", g.cfg.Executor, g.cfg.VirtualUsers()))
+	} else {
+		b.WriteString(fmt.Sprintf("  Target RPS:    %d\n", g.cfg.TargetRPS))
+	}
+	b.WriteString(fmt.Sprintf("  Seed:          %d\n", g.cfg.Seed))
+	b.WriteString("\n")
+}
+
+// writeRuns writes a table with the totals of the median run of every variant,
+// labelled by the variant name, and the variation between repeated runs.
+func writeRuns(b *strings.Builder, label string, results []matrix.Result) {
+	b.WriteString(fmt.Sprintf("  %-34s %8s %8s %7s %9s %9s %9s\n", label, "Requests", "RPS", "Errors", "Mean", "P95", "P99"))
+	for _, r := range results {
+		s := r.Snapshot
+		b.WriteString(fmt.Sprintf("  %-34s %8d %8.1f %6.2f%% %9s %9s %9s\n",
+			truncate(r.Variant.Name, 34),
+			s.TotalRequests,
+			s.AverageRPS,
+			s.ErrorRate,
+			formatDuration(s.LatencyMean),
+			formatDuration(s.LatencyP95),
+			formatDuration(s.LatencyP99),
+		))
+	}
+	b.WriteString("\n")
+
+	repeated := false
+	for _, r := range results {
+		repeated = repeated || r.Runs > 1
+	}
+	if !repeated {
+		return
+	}
+	b.WriteString("Run-to-Run Variation (standard deviation; the table above shows the median run):\n")
+	b.WriteString(strings.Repeat("-", 80) + "\n")
+	b.WriteString(fmt.Sprintf("  %-34s %4s %8s %9s %9s %9s\n", label, "Runs", "RPS", "Mean", "P95", "P99"))
+	for _, r := range results {
+		b.WriteString(fmt.Sprintf("  %-34s %4d %8.1f %9s %9s %9s\n",
+			truncate(r.Variant.Name, 34),
+			r.Runs,
+			r.Spread.RPS,
+			formatDuration(r.Spread.LatencyMean),
+			formatDuration(r.Spread.LatencyP95),
+			formatDuration(r.Spread.LatencyP99),
+		))
+	}
+	b.WriteString("\n")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,18 +30,15 @@ func New(cfg *config.Config, m *metrics.Metrics) *Generator {
 // Generate generates and writes the report.
 func (g *Generator) Generate() error {
 	snapshot := g.metrics.Snapshot()
+	if !g.cfg.Breakdown && g.cfg.TestType != config.TestTypeReplay {
+		snapshot.Endpoints = nil
+	}
 
-	var writer io.Writer
-	if g.cfg.ReportFile != "" {
-		file, err := os.Create(g.cfg.ReportFile)
-		if err != nil {
-			return fmt.Errorf("failed to create report file: %w", err)
-		}
-		defer file.Close()
-		writer = file
-	} else {
-		writer = os.Stdout
+	writer, closeWriter, err := g.output()
+	if err != nil {
+		return err
 	}
+	defer closeWriter()
 
 	switch g.cfg.ReportFormat {
 	case "json":
@@ -52,6 +50,18 @@ func (g *Generator) Generate() error {
 	}
 }
 
+// output returns the writer the report is written to and a function closing it.
+func (g *Generator) output() (io.Writer, func() error, error) {
+	if g.cfg.ReportFile == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(g.cfg.ReportFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create report file: %w", err)
+	}
+	return file, file.Close, nil
+}
+
 // generateJSON generates a JSON report.
 func (g *Generator) generateJSON(w io.Writer, s metrics.Snapshot) error {
 	enc := json.NewEncoder(w)
@@ -79,9 +89,62 @@ func (g *Generator) generateText(w io.Writer, s metrics.Snapshot) error {
 	b.WriteString("Test Configuration:\n")
 	b.WriteString(strings.Repeat("-", 80) + "\n")
 	b.WriteString(fmt.Sprintf("  Test Type:     %s\n", g.cfg.TestType))
-	b.WriteString(fmt.Sprintf("  Server Addr:   %s\n", g.cfg.ServerAddr))
+	if g.cfg.TargetURL != "" {
+		b.WriteString(fmt.Sprintf("  Target URL:    %s\n", g.cfg.TargetURL))
+	} else {
+		b.WriteString(fmt.Sprintf("  Server Addr:   %s\n", g.cfg.ServerAddr))
+	}
+	if g.cfg.EmbeddedServer() {
+		b.WriteString(fmt.Sprintf("  Server:        %s, %s store\n", g.cfg.ServerImpl, g.cfg.Store))
+	}
+	if g.cfg.Mode == config.ModeCoordinator {
+		b.WriteString(fmt.Sprintf("  Agents:        %d (%s)\n", len(g.cfg.Agents), strings.Join(g.cfg.Agents, ", ")))
+	}
+	protocol := string(g.cfg.Protocol)
+	if strings.HasPrefix(g.cfg.BaseURL(), "https://") {
+		protocol += " (TLS)"
+	}
+	b.WriteString(fmt.Sprintf("  Protocol:      %s\n", protocol))
 	b.WriteString(fmt.Sprintf("  Concurrent:    %d\n", g.cfg.Concurrent))
-	b.WriteString(fmt.Sprintf("  Target RPS:    %d\n", g.cfg.TargetRPS))
+	connections := "keep-alive"
+	if !g.cfg.KeepAlive {
+		connections = "new connection per request"
+	} else if g.cfg.ConnChurn > 0 {
+		connections = fmt.Sprintf("keep-alive, closed every %d requests", g.cfg.ConnChurn)
+	}
+	if g.cfg.MaxConnsPerHost > 0 {
+		connections += fmt.Sprintf(", max %d per host", g.cfg.MaxConnsPerHost)
+	}
+	b.WriteString(fmt.Sprintf("  Connections:   %s\n", connections))
+	if g.cfg.SlowClients > 0 {
+		modes := make([]string, len(g.cfg.SlowModes))
+		for i, mode := range g.cfg.SlowModes {
+			modes[i] = string(mode)
+		}
+		b.WriteString(fmt.Sprintf("  Slow Clients:  %d (%s, 1 byte every %s, from %s)\n", g.cfg.SlowClients, strings.Join(modes, ", "), g.cfg.SlowInterval, g.cfg.SlowStart))
+	}
+	if g.cfg.Warmup > 0 {
+		b.WriteString(fmt.Sprintf("  Warm-up:       %s\n", g.cfg.Warmup))
+	}
+	if g.cfg.Executor == config.ExecutorVU {
+		b.WriteString(fmt.Sprintf("  Executor:      %s (%d virtual users)\n", g.cfg.Executor, g.cfg.VirtualUsers()))
+		b.WriteString(fmt.Sprintf("  Think Time:    %s\n", g.cfg.ThinkTime))
+		if g.cfg.Pacing > 0 {
+			b.WriteString(fmt.Sprintf("  Pacing:        %s\n", g.cfg.Pacing))
+		}
+	} else {
+		b.WriteString(fmt.Sprintf("  Target RPS:    %d\n", g.cfg.TargetRPS))
+		b.WriteString(fmt.Sprintf("  Arrivals:      %s\n", g.cfg.Arrival))
+	}
+	b.WriteString(fmt.Sprintf("  Seed:          %d\n", g.cfg.Seed))
+	if g.cfg.TestType == config.TestTypeReplay {
+		b.WriteString(fmt.Sprintf("  Replay File:   %s\n", g.cfg.ReplayFile))
+		if g.cfg.ReplaySpeed == 0 {
+			b.WriteString("  Replay Speed:  as fast as possible\n")
+		} else {
+			b.WriteString(fmt.Sprintf("  Replay Speed:  %.2fx\n", g.cfg.ReplaySpeed))
+		}
+	}
 	b.WriteString("\n")
 
 	// Request Statistics
@@ -90,13 +153,43 @@ func (g *Generator) generateText(w io.Writer, s metrics.Snapshot) error {
 	b.WriteString(fmt.Sprintf("  Total Requests:    %d\n", s.TotalRequests))
 	b.WriteString(fmt.Sprintf("  Success Requests:  %d (%.2f%%)\n", s.SuccessRequests, float64(s.SuccessRequests)/float64(s.TotalRequests)*100))
 	b.WriteString(fmt.Sprintf("  Error Requests:    %d (%.2f%%)\n", s.ErrorRequests, s.ErrorRate))
+	if len(s.Protocols) > 0 {
+		protos := make([]string, 0, len(s.Protocols))
+		for proto := range s.Protocols {
+			protos = append(protos, proto)
+		}
+		sort.Strings(protos)
+		for _, proto := range protos {
+			b.WriteString(fmt.Sprintf("  %-18s %d responses\n", proto+":", s.Protocols[proto]))
+		}
+	}
 	b.WriteString("\n")
 
+	// Connections
+	if s.ConnsOpened > 0 || s.ConnsReused > 0 {
+		b.WriteString("Connections:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  Opened:  %d\n", s.ConnsOpened))
+		b.WriteString(fmt.Sprintf("  Reused:  %d requests\n", s.ConnsReused))
+		b.WriteString(fmt.Sprintf("  Closed:  %d\n", s.ConnsClosed))
+		if s.ConnsOpened > 0 {
+			b.WriteString(fmt.Sprintf("  Requests per Connection: %.2f\n", float64(s.TotalRequests)/float64(s.ConnsOpened)))
+		}
+		b.WriteString("\n")
+	}
+
 	// Throughput
 	b.WriteString("Throughput:\n")
 	b.WriteString(strings.Repeat("-", 80) + "\n")
 	b.WriteString(fmt.Sprintf("  Current RPS:  %d\n", s.CurrentRPS))
 	b.WriteString(fmt.Sprintf("  Average RPS:  %.2f\n", s.AverageRPS))
+	if s.Arrivals > 0 {
+		b.WriteString(fmt.Sprintf("  Queued:       %d of %d arrivals (queue delay mean %s, max %s)\n",
+			s.QueuedArrivals, s.Arrivals, formatDuration(s.QueueDelayMean), formatDuration(s.QueueDelayMax)))
+	}
+	if s.Iterations > 0 {
+		b.WriteString(fmt.Sprintf("  Iterations:   %d (mean %s)\n", s.Iterations, formatDuration(s.IterationMean)))
+	}
 	b.WriteString("\n")
 
 	// Latency
@@ -115,6 +208,23 @@ func (g *Generator) generateText(w io.Writer, s metrics.Snapshot) error {
 	}
 	b.WriteString("\n")
 
+	// Warm-up
+	if w := s.Warmup; w != nil {
+		b.WriteString("Warm-up (excluded from results):\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  Requests:  %d (%.2f%% errors)\n", w.TotalRequests, w.ErrorRate))
+		if w.LatencyMin > 0 {
+			b.WriteString(fmt.Sprintf("  Latency:   min %s, mean %s, P99 %s, P99.9 %s, max %s\n",
+				formatDuration(w.LatencyMin),
+				formatDuration(w.LatencyMean),
+				formatDuration(w.LatencyP99),
+				formatDuration(w.LatencyP999),
+				formatDuration(w.LatencyMax),
+			))
+		}
+		b.WriteString("\n")
+	}
+
 	// Error Breakdown
 	if len(s.ErrorsByStatus) > 0 {
 		b.WriteString("Error Breakdown:\n")
@@ -125,6 +235,203 @@ func (g *Generator) generateText(w io.Writer, s metrics.Snapshot) error {
 		b.WriteString("\n")
 	}
 
+	// Endpoint Breakdown
+	if len(s.Endpoints) > 0 {
+		b.WriteString("Endpoint Breakdown:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-32s %9s %8s %10s %10s %10s\n", "Endpoint", "Requests", "Errors", "Mean", "P95", "P99"))
+		for _, e := range s.Endpoints {
+			b.WriteString(fmt.Sprintf("  %-32s %9d %7.2f%% %10s %10s %10s\n",
+				truncate(e.Name, 32),
+				e.TotalRequests,
+				e.ErrorRate,
+				formatDuration(e.LatencyMean),
+				formatDuration(e.LatencyP95),
+				formatDuration(e.LatencyP99),
+			))
+		}
+		b.WriteString("\n")
+	}
+
+	// Phases
+	if len(s.Phases) > 0 {
+		b.WriteString("Phases:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-12s %8s %8s %8s %8s %9s %9s %9s\n", "Phase", "Duration", "Requests", "RPS", "Errors", "Mean", "P95", "P99"))
+		for _, p := range s.Phases {
+			b.WriteString(fmt.Sprintf("  %-12s %8s %8d %8.1f %7.2f%% %9s %9s %9s\n",
+				truncate(p.Name, 12),
+				p.Duration.Round(time.Second),
+				p.TotalRequests,
+				p.AverageRPS,
+				p.ErrorRate,
+				formatDuration(p.LatencyMean),
+				formatDuration(p.LatencyP95),
+				formatDuration(p.LatencyP99),
+			))
+		}
+		b.WriteString("\n")
+
+		var degraded []metrics.PhaseStats
+		for _, p := range s.Phases {
+			if p.MeanRatio > 0 {
+				degraded = append(degraded, p)
+			}
+		}
+		if len(degraded) > 0 {
+			b.WriteString("Degradation vs Baseline:\n")
+			b.WriteString(strings.Repeat("-", 80) + "\n")
+			b.WriteString(fmt.Sprintf("  %-12s %10s %10s %10s %12s\n", "Phase", "Mean", "P95", "P99", "Errors"))
+			for _, p := range degraded {
+				b.WriteString(fmt.Sprintf("  %-12s %9.2fx %9.2fx %9.2fx %+10.2fpp\n",
+					truncate(p.Name, 12),
+					p.MeanRatio,
+					p.P95Ratio,
+					p.P99Ratio,
+					p.ErrorRateDelta,
+				))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// Slow Clients
+	if len(s.SlowClients) > 0 {
+		b.WriteString("Slow Clients:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-8s %8s %9s %8s %6s %6s %10s %10s\n", "Mode", "Conns", "Completed", "Closed", "Held", "Failed", "Mean Held", "Max Held"))
+		for _, c := range s.SlowClients {
+			b.WriteString(fmt.Sprintf("  %-8s %8d %9d %8d %6d %6d %10s %10s\n",
+				c.Mode,
+				c.Connections,
+				c.Completed,
+				c.ClosedByServer,
+				c.Held,
+				c.Failed,
+				formatDuration(c.MeanHeld.Round(time.Millisecond)),
+				formatDuration(c.MaxHeld.Round(time.Millisecond)),
+			))
+		}
+		b.WriteString("  Closed: closed or reset by the server; Held: still open when the test ended\n")
+		b.WriteString("\n")
+	}
+
+	// Streams
+	if len(s.Streams) > 0 {
+		b.WriteString("Streams:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-22s %7s %6s %8s %9s %9s %9s %9s\n", "Endpoint", "Streams", "Cut", "Events", "Events/s", "Mean TTFE", "Mean Dur", "Bytes"))
+		for _, st := range s.Streams {
+			b.WriteString(fmt.Sprintf("  %-22s %7d %6d %8d %9.1f %9s %9s %9s\n",
+				truncate(st.Endpoint, 22),
+				st.Streams,
+				st.Incomplete,
+				st.Events,
+				st.EventRate,
+				formatDuration(st.MeanFirstEvent.Round(time.Microsecond)),
+				formatDuration(st.MeanDuration.Round(time.Millisecond)),
+				formatBytes(uint64(st.Bytes)),
+			))
+		}
+		b.WriteString("  TTFE: time to first event; Cut: ended by an error or the end of the test\n")
+		b.WriteString("\n")
+	}
+
+	// Dataset
+	if len(s.Datasets) > 0 {
+		b.WriteString("Dataset:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-20s %-9s %10s %10s %18s\n", "When", "Action", "Items", "Next ID", "Checksum"))
+		for _, d := range s.Datasets {
+			action := "snapshot"
+			if d.Reseeded {
+				action = "reseeded"
+			}
+			if d.Error != "" {
+				b.WriteString(fmt.Sprintf("  %-20s %-9s failed: %s\n", truncate(d.Label, 20), action, d.Error))
+				continue
+			}
+			b.WriteString(fmt.Sprintf("  %-20s %-9s %10d %10d %18s\n", truncate(d.Label, 20), action, d.Items, d.NextID, d.Checksum))
+		}
+		b.WriteString("\n")
+	}
+
+	// Verification
+	if v := s.Verification; v != nil {
+		b.WriteString("Verification:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		if v.Error != "" {
+			b.WriteString(fmt.Sprintf("  Failed: %s\n\n", v.Error))
+		} else {
+			b.WriteString(fmt.Sprintf("  Acknowledged Writes: %d creates, %d updates, %d deletes\n", v.Creates, v.Updates, v.Deletes))
+			b.WriteString(fmt.Sprintf("  Unacknowledged:      %d (outcome unknown, not checked)\n", v.Unacknowledged))
+			b.WriteString(fmt.Sprintf("  Final Items:         %d\n", v.Items))
+			b.WriteString(fmt.Sprintf("  Missing Items:       %d\n", v.Missing))
+			b.WriteString(fmt.Sprintf("  Lost Deletes:        %d\n", v.LostDeletes))
+			b.WriteString(fmt.Sprintf("  Lost Updates:        %d\n", v.LostUpdates))
+			b.WriteString(fmt.Sprintf("  Unknown Values:      %d\n", v.UnknownValues))
+			b.WriteString(fmt.Sprintf("  Unrecorded Items:    %d\n", v.Unrecorded))
+			if n := v.Violations(); n > 0 {
+				b.WriteString(fmt.Sprintf("  Result:              FAILED (%d violations)\n", n))
+				for _, example := range v.Examples {
+					b.WriteString(fmt.Sprintf("    - %s\n", example))
+				}
+			} else {
+				b.WriteString("  Result:              PASSED\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// Linearizability
+	if l := s.Linearizability; l != nil {
+		b.WriteString("Linearizability:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  Items Checked:       %d\n", l.Items))
+		b.WriteString(fmt.Sprintf("  Operations:          %d (%d without a response)\n", l.Operations, l.Pending))
+		b.WriteString(fmt.Sprintf("  Not Linearizable:    %d\n", l.Violations))
+		b.WriteString(fmt.Sprintf("  Undecided:           %d (search limit reached)\n", l.Unknown))
+		if l.Violations > 0 {
+			b.WriteString(fmt.Sprintf("  Result:              FAILED (%d items)\n", l.Violations))
+			for _, v := range l.Examples {
+				b.WriteString(fmt.Sprintf("    Item %d (%d operations), minimal offending operations:\n", v.Item, v.Operations))
+				for _, op := range v.Offending {
+					b.WriteString(fmt.Sprintf("      %s\n", op))
+				}
+			}
+		} else {
+			b.WriteString("  Result:              PASSED\n")
+		}
+		b.WriteString("\n")
+	}
+
+	// Spikes
+	if len(s.Spikes) > 0 {
+		b.WriteString("Spikes:\n")
+		b.WriteString(strings.Repeat("-", 80) + "\n")
+		b.WriteString(fmt.Sprintf("  %-3s %8s %8s %-11s %10s %10s %8s %12s\n", "#", "Offset", "Peak", "Shape", "Base Lat", "Peak Lat", "Peak Err", "Recovery"))
+		for _, sp := range s.Spikes {
+			recovery, baseline := "not recovered", formatDuration(sp.BaselineLatency)
+			switch {
+			case !sp.HasBaseline:
+				recovery, baseline = "no baseline", "-"
+			case sp.Recovered:
+				recovery = formatDuration(sp.RecoveryTime)
+			}
+			b.WriteString(fmt.Sprintf("  %-3d %8s %8d %-11s %10s %10s %7.2f%% %12s\n",
+				sp.Index,
+				sp.Offset.Round(time.Second),
+				sp.PeakRPS,
+				sp.Shape,
+				baseline,
+				formatDuration(sp.PeakLatency),
+				sp.PeakErrRate,
+				recovery,
+			))
+		}
+		b.WriteString("\n")
+	}
+
 	// Memory Statistics
 	b.WriteString("Memory Statistics:\n")
 	b.WriteString(strings.Repeat("-", 80) + "\n")
@@ -155,6 +462,14 @@ func formatDuration(d time.Duration) string {
 	return d.String()
 }
 
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
 // formatBytes formats bytes in a human-readable way.
 func formatBytes(b uint64) string {
 	const unit = 1024
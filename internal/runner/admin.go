@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// adminTokenHeader carries the token of the server's admin routes.
+const adminTokenHeader = "X-Admin-Token"
+
+// storeSnapshot is the response of the admin routes managing the dataset.
+type storeSnapshot struct {
+	Items    int    `json:"items"`
+	NextID   int    `json:"next_id"`
+	Checksum string `json:"checksum"`
+}
+
+// RecordDataset records a snapshot of the server's dataset under label and, with
+// reseed, reseeds it and records the snapshot of the restored dataset. It does
+// nothing without an admin token. Failures are recorded and returned. Run calls
+// it before and after the test; in distributed mode the coordinator calls it
+// instead, once for all agents.
+func (r *Runner) RecordDataset(ctx context.Context, label string, reseed bool) error {
+	if r.cfg.AdminToken == "" {
+		return nil
+	}
+
+	err := r.adminSnapshot(ctx, label, false, http.MethodGet, "/admin/snapshot")
+	if err == nil && reseed {
+		err = r.adminSnapshot(ctx, label, true, http.MethodPost, "/admin/reseed")
+	}
+	return err
+}
+
+// phaseBoundary reseeds the dataset at the start of a phase when configured to.
+// The writes logged for verification are then checked against the new dataset.
+func (r *Runner) phaseBoundary(ctx context.Context, phase string) {
+	if r.cfg.Reseed != config.ReseedPhase {
+		return
+	}
+	if r.writes != nil {
+		r.writes.clear()
+	}
+	_ = r.RecordDataset(ctx, phase, true)
+	_ = r.startVerification(ctx)
+}
+
+// adminSnapshot calls an admin route returning a snapshot and records it.
+func (r *Runner) adminSnapshot(ctx context.Context, label string, reseeded bool, method, path string) error {
+	record := metrics.DatasetSnapshot{At: time.Now(), Label: label, Reseeded: reseeded}
+	var snapshot storeSnapshot
+	err := r.adminRequest(ctx, method, path, &snapshot)
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Items = snapshot.Items
+		record.NextID = snapshot.NextID
+		record.Checksum = snapshot.Checksum
+	}
+	r.metrics.RecordDataset(record)
+	return err
+}
+
+// adminRequest calls an admin route of the server and decodes its response into v.
+func (r *Runner) adminRequest(ctx context.Context, method, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create admin request: %w", err)
+	}
+	req.Header.Set(adminTokenHeader, r.cfg.AdminToken)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("admin request %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("admin request %s %s failed: status %d", method, path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode admin response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+)
+
+// arrivalProcess generates request arrival times following the configured distribution.
+type arrivalProcess struct {
+	arrival  config.Arrival
+	interval time.Duration // Mean interval between arrivals
+	rng      *rand.Rand
+	start    time.Time
+
+	// shape scales the rate over time (1 is the full rate); nil keeps the rate constant
+	shape func(elapsed time.Duration) float64
+}
+
+// dispatchArrivals calls send on its own goroutine at every arrival of a process
+// with the given rate, scaled over time by shape if it isn't nil, until ctx is done.
+// n counts the arrivals from 0. At most limit sends are in flight: an arrival that
+// finds them all busy waits for one to finish, and later arrivals queue behind it.
+// Arrivals are never dropped; the delay from the scheduled time of each arrival to
+// its send is recorded as its queueing delay. It returns once every send is done.
+func (r *Runner) dispatchArrivals(ctx context.Context, rate, limit int, shape func(elapsed time.Duration) float64, send func(ctx context.Context, n int)) {
+	r.rngMu.Lock()
+	seed := r.rng.Int63()
+	r.rngMu.Unlock()
+
+	p := &arrivalProcess{
+		arrival:  r.cfg.Arrival,
+		interval: time.Second / time.Duration(rate),
+		rng:      rand.New(rand.NewSource(seed)),
+		start:    time.Now(),
+		shape:    shape,
+	}
+
+	slots := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	next := p.start
+	for n := 0; ; n++ {
+		next = p.next(next)
+		if !sleepContext(ctx, timer, time.Until(next)) {
+			return
+		}
+
+		queued := false
+		select {
+		case slots <- struct{}{}:
+		default:
+			queued = true
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		r.metrics.RecordArrival(time.Since(next), queued)
+
+		wg.Add(1)
+		go func(n int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			send(ctx, n)
+		}(n)
+	}
+}
+
+// next returns the arrival following prev. Times are scheduled from the previous
+// arrival rather than the current time, so the mean rate holds even if the generator
+// falls behind.
+func (p *arrivalProcess) next(prev time.Time) time.Time {
+	interval := p.interval
+	if p.shape != nil {
+		// Never drop below one arrival per second, so a rate of zero cannot stall the process
+		scale := p.shape(prev.Sub(p.start))
+		if minScale := float64(p.interval) / float64(time.Second); scale < minScale {
+			scale = minScale
+		}
+		interval = time.Duration(float64(interval) / scale)
+	}
+
+	switch p.arrival.Kind {
+	case config.ArrivalPoisson:
+		return prev.Add(time.Duration(p.rng.ExpFloat64() * float64(interval)))
+
+	case config.ArrivalUniform:
+		jitter := p.arrival.Jitter * (2*p.rng.Float64() - 1)
+		return prev.Add(time.Duration(float64(interval) * (1 + jitter)))
+
+	case config.ArrivalBursty:
+		// Compress the arrivals of a whole on/off cycle into the on period,
+		// keeping the same average rate.
+		on, off := p.arrival.BurstOn, p.arrival.BurstOff
+		cycle := on + off
+		next := prev.Add(time.Duration(float64(interval) * float64(on) / float64(cycle)))
+		if pos := next.Sub(p.start) % cycle; pos >= on {
+			next = next.Add(cycle - pos)
+		}
+		return next
+
+	default:
+		return prev.Add(interval)
+	}
+}
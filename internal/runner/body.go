@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"mime/multipart"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Encodings of generated request bodies.
+const (
+	BodyJSON      = "json"      // {"name":"test","value":"..."} with a value of SIZE characters
+	BodyForm      = "form"      // name=test&value=... urlencoded, with a value of SIZE characters
+	BodyMultipart = "multipart" // Multipart form with name and value fields and a file of SIZE bytes
+	BodyBinary    = "binary"    // SIZE random bytes as application/octet-stream
+)
+
+// maxGeneratedBody bounds the size of generated bodies, which are held in memory.
+const maxGeneratedBody = 1 << 30
+
+// generatedBody is a request body generated once and sent by every request of an
+// endpoint.
+type generatedBody struct {
+	data        []byte
+	contentType string
+}
+
+// parseBodySpec generates the body described by a KIND:SIZE spec, such as
+// "multipart:1MB".
+func parseBodySpec(spec string) (*generatedBody, error) {
+	kind, sizeStr, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid body %q (expected KIND:SIZE)", spec)
+	}
+	size, err := parseSize(sizeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body %q: %w", spec, err)
+	}
+	if size > maxGeneratedBody {
+		return nil, fmt.Errorf("invalid body %q: size exceeds 1GB", spec)
+	}
+	return generateBody(strings.ToLower(kind), size)
+}
+
+// parseSize parses a byte size such as 512, 64KB or 1MB. Units are powers of 1024.
+func parseSize(size string) (int, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	unit := 1
+	for _, u := range []struct {
+		suffix string
+		size   int
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSuffix(s, u.suffix), u.size
+			break
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > math.MaxInt/unit {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * unit, nil
+}
+
+// generateBody returns a body of the given encoding carrying size bytes of
+// payload. The payload is pseudo-random but the same on every run.
+func generateBody(kind string, size int) (*generatedBody, error) {
+	rng := rand.New(rand.NewSource(1))
+
+	switch kind {
+	case BodyJSON:
+		var b bytes.Buffer
+		b.Grow(size + 32)
+		b.WriteString(`{"name":"test","value":"`)
+		b.Write(alphanumeric(rng, size))
+		b.WriteString(`"}`)
+		return &generatedBody{data: b.Bytes(), contentType: "application/json"}, nil
+
+	case BodyForm:
+		form := url.Values{"name": {"test"}, "value": {string(alphanumeric(rng, size))}}
+		return &generatedBody{data: []byte(form.Encode()), contentType: "application/x-www-form-urlencoded"}, nil
+
+	case BodyMultipart:
+		var b bytes.Buffer
+		b.Grow(size + 512)
+		w := multipart.NewWriter(&b)
+		if err := w.WriteField("name", "test"); err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		if err := w.WriteField("value", "test"); err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		part, err := w.CreateFormFile("file", "upload.bin")
+		if err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		data := make([]byte, size)
+		rng.Read(data)
+		if _, err := part.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write multipart body: %w", err)
+		}
+		return &generatedBody{data: b.Bytes(), contentType: w.FormDataContentType()}, nil
+
+	case BodyBinary:
+		data := make([]byte, size)
+		rng.Read(data)
+		return &generatedBody{data: data, contentType: "application/octet-stream"}, nil
+	}
+	return nil, fmt.Errorf("invalid body encoding %q (expected %s, %s, %s or %s)", kind, BodyJSON, BodyForm, BodyMultipart, BodyBinary)
+}
+
+// alphanumeric returns n random lowercase alphanumeric characters.
+func alphanumeric(rng *rand.Rand, n int) []byte {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return b
+}
@@ -0,0 +1,15 @@
+package runner
+
+import "time"
+
+// Flow is an ordered sequence of requests replayed by a single virtual user.
+type Flow struct {
+	Name  string
+	Steps []FlowStep
+}
+
+// FlowStep is a request in a flow, sent after its think time.
+type FlowStep struct {
+	Endpoint  Endpoint
+	ThinkTime time.Duration
+}
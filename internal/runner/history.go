@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/linearizability"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// historyStepLimit bounds the search of each item history, so a long history of a
+// hot item can't stall the report.
+const historyStepLimit = 1_000_000
+
+// historyLog records the operations on every item, to check offline that each
+// item behaves like a linearizable register.
+type historyLog struct {
+	mu     sync.Mutex
+	origin time.Time                           // Operation times are relative to it
+	ops    map[int][]linearizability.Operation // Operations by item ID
+}
+
+// newHistoryLog creates an empty history log.
+func newHistoryLog() *historyLog {
+	return &historyLog{
+		origin: time.Now(),
+		ops:    make(map[int][]linearizability.Operation),
+	}
+}
+
+// begin restarts the log at the start of the run.
+func (l *historyLog) begin() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.origin = time.Now()
+	l.ops = make(map[int][]linearizability.Operation)
+}
+
+// record adds an operation called at start and returned at end to the history
+// of item id.
+func (l *historyLog) record(id int, start, end time.Time, op linearizability.Operation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	op.Call = start.Sub(l.origin)
+	op.Return = end.Sub(l.origin)
+	l.ops[id] = append(l.ops[id], op)
+}
+
+// itemValue is the value of an item in the register model.
+func itemValue(it item) string {
+	return it.Name + ":" + it.Value
+}
+
+// logOperation records a request to an item in its history. status is 0 if no
+// complete response was received; such writes may or may not have taken effect.
+// Reads and creates without a response, whose item is unknown, and requests the
+// server rejected are not recorded.
+func (r *Runner) logOperation(kind itemOp, id int, start time.Time, sent string, status int, body []byte) {
+	if r.history == nil {
+		return
+	}
+	end := time.Now()
+
+	var op linearizability.Operation
+	switch kind {
+	case opCreate:
+		op.Kind = linearizability.Create
+	case opRead:
+		op.Kind = linearizability.Read
+	case opUpdate:
+		op.Kind = linearizability.Update
+	case opDelete:
+		op.Kind = linearizability.Delete
+	}
+	var it item
+	if err := json.Unmarshal([]byte(sent), &it); err == nil {
+		op.Input = itemValue(it)
+	}
+
+	switch {
+	case status >= 200 && status < 300:
+		op.Found = true
+		if kind != opDelete {
+			var got item
+			if err := json.Unmarshal(body, &got); err != nil || got.ID == 0 {
+				op.Pending = true
+				break
+			}
+			id, op.Output = got.ID, itemValue(got)
+		}
+	case status == http.StatusNotFound && kind != opCreate:
+		// The item didn't exist
+	case status == 0 || status >= 500:
+		op.Pending = true
+	default:
+		return
+	}
+	if op.Pending && (kind == opRead || kind == opCreate) {
+		return
+	}
+	r.history.record(id, start, end, op)
+}
+
+// checkHistories checks the history of every item and records the result.
+func (r *Runner) checkHistories() {
+	r.metrics.SetLinearizability(r.history.check())
+}
+
+// check checks the history of every item, in ID order, and minimizes the first
+// violations found.
+func (l *historyLog) check() metrics.Linearizability {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids := make([]int, 0, len(l.ops))
+	for id := range l.ops {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	res := metrics.Linearizability{Items: len(ids)}
+	for _, id := range ids {
+		ops := l.ops[id]
+		res.Operations += int64(len(ops))
+		for _, op := range ops {
+			if op.Pending {
+				res.Pending++
+			}
+		}
+
+		switch linearizability.Check(ops, historyStepLimit) {
+		case linearizability.NotLinearizable:
+			res.Violations++
+			if res.WantsExample() {
+				violation := metrics.LinearizabilityViolation{Item: id, Operations: len(ops)}
+				for _, op := range linearizability.Minimize(ops, historyStepLimit) {
+					violation.Offending = append(violation.Offending, op.String())
+				}
+				res.Examples = append(res.Examples, violation)
+			}
+		case linearizability.Unknown:
+			res.Unknown++
+		}
+	}
+	return res
+}
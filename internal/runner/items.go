@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// itemOp is the kind of a request to a single item.
+type itemOp int
+
+const (
+	opCreate itemOp = iota + 1
+	opRead
+	opUpdate
+	opDelete
+)
+
+// itemPath matches the item routes whose requests are logged for verification and
+// the linearizability check.
+var itemPath = regexp.MustCompile(`^/items(?:/([0-9]+))?$`)
+
+// classifyItemRequest returns the kind of item request a request is and the item
+// ID in its path, or 0 if it isn't one. Creates have no ID until they complete.
+func classifyItemRequest(method, path string) (itemOp, int) {
+	path, _, _ = strings.Cut(path, "?")
+	m := itemPath.FindStringSubmatch(path)
+	if m == nil {
+		return 0, 0
+	}
+	if m[1] == "" {
+		if method == http.MethodPost {
+			return opCreate, 0
+		}
+		return 0, 0
+	}
+
+	id, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0
+	}
+	switch method {
+	case http.MethodGet:
+		return opRead, id
+	case http.MethodPut, http.MethodPatch:
+		return opUpdate, id
+	case http.MethodDelete:
+		return opDelete, id
+	}
+	return 0, 0
+}
+
+// item is an item as returned by the server.
+type item struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// logItemRequest records a request to an item in the write log and the item
+// histories. status is 0 if no complete response was received, and body is the
+// response body, except for deletes.
+func (r *Runner) logItemRequest(op itemOp, id int, start time.Time, sent string, status int, body []byte) {
+	if op == 0 {
+		return
+	}
+	r.logWrite(op, id, start, sent, status, body)
+	r.logOperation(op, id, start, sent, status, body)
+}
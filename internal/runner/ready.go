@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// readyPollInterval is the delay between readiness probes.
+const readyPollInterval = 100 * time.Millisecond
+
+// WaitReady probes the health path until the server answers with a 2xx status or
+// the ready timeout expires. It returns immediately if no health path is configured.
+func (r *Runner) WaitReady(ctx context.Context) error {
+	if r.cfg.HealthPath == "" {
+		return nil
+	}
+
+	url := r.baseURL + r.cfg.HealthPath
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.ReadyTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create readiness probe: %w", err)
+		}
+
+		resp, err := r.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if ctx.Err() == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return fmt.Errorf("server at %s not ready after %s: %w", url, r.cfg.ReadyTimeout, lastErr)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayEntry is a single request recorded in a JSON-lines request log.
+//
+// Example line:
+//
+//	{"method":"GET","path":"/items/42","headers":{"Accept":"application/json"},"offset_ms":120}
+//
+// Body may be a JSON string or any JSON value, which is sent as-is.
+// OffsetMS is the time since the start of the recording; entries without
+// an offset are sent as soon as they are reached.
+type ReplayEntry struct {
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	OffsetMS int64             `json:"offset_ms,omitempty"`
+	Template string            `json:"template,omitempty"` // Optional name for the per-template breakdown
+}
+
+// replayRequest is a parsed replay entry ready to be sent.
+type replayRequest struct {
+	endpoint Endpoint
+	offset   time.Duration
+}
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// LoadReplayLog reads a JSON-lines request log from path.
+// Blank lines are skipped and entries are ordered by their offset.
+func LoadReplayLog(path string) ([]ReplayEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []ReplayEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var entry ReplayEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("invalid replay entry on line %d: %w", line, err)
+		}
+		if entry.Method == "" || entry.Path == "" {
+			return nil, fmt.Errorf("invalid replay entry on line %d: method and path are required", line)
+		}
+		if entry.OffsetMS < 0 {
+			return nil, fmt.Errorf("invalid replay entry on line %d: offset_ms cannot be negative", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read replay file: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].OffsetMS < entries[j].OffsetMS
+	})
+
+	return entries, nil
+}
+
+// TemplateName returns the name used to group the entry in the per-template breakdown.
+// Numeric and UUID path segments are replaced by placeholders and the query string is dropped.
+func (e ReplayEntry) TemplateName() string {
+	method := strings.ToUpper(e.Method)
+	if e.Template != "" {
+		return method + " " + e.Template
+	}
+
+	path, _, _ := strings.Cut(e.Path, "?")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case numericSegment.MatchString(segment):
+			segments[i] = "{id}"
+		case uuidSegment.MatchString(segment):
+			segments[i] = "{uuid}"
+		}
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+// endpoint converts the entry into an Endpoint.
+func (e ReplayEntry) endpoint() Endpoint {
+	var body string
+	if len(e.Body) > 0 && string(e.Body) != "null" {
+		var text string
+		if err := json.Unmarshal(e.Body, &text); err == nil {
+			body = text
+		} else {
+			body = string(e.Body)
+		}
+	}
+
+	return Endpoint{
+		Name:         e.TemplateName(),
+		Method:       strings.ToUpper(e.Method),
+		Path:         e.Path,
+		Headers:      e.Headers,
+		Body:         body,
+		HasDynamicID: hasPlaceholders(e.Path),
+	}
+}
+
+// runReplayTest replays a recorded request log.
+// With a positive replay speed requests are sent at their recorded offsets divided by the speed;
+// with a speed of 0 they are sent as fast as the concurrent workers allow.
+func (r *Runner) runReplayTest(ctx context.Context) error {
+	entries, err := LoadReplayLog(r.cfg.ReplayFile)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("replay file %s contains no requests", r.cfg.ReplayFile)
+	}
+
+	requests := make([]replayRequest, 0, len(entries))
+	for _, entry := range entries {
+		ep := entry.endpoint()
+		switch ep.Method {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+			// Valid
+		default:
+			return fmt.Errorf("invalid HTTP method in replay file: %s", ep.Method)
+		}
+		requests = append(requests, replayRequest{
+			endpoint: ep,
+			offset:   time.Duration(entry.OffsetMS) * time.Millisecond,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Warmup+r.cfg.Duration)
+	defer cancel()
+
+	if r.cfg.ReplaySpeed == 0 {
+		r.replayUnpaced(ctx, requests)
+	} else {
+		r.replayTimed(ctx, requests)
+	}
+	return nil
+}
+
+// replayTimed sends each request at its scaled offset, regardless of how many are in flight.
+func (r *Runner) replayTimed(ctx context.Context, requests []replayRequest) {
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, req := range requests {
+		due := start.Add(time.Duration(float64(req.offset) / r.cfg.ReplaySpeed))
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		wg.Add(1)
+		go func(ep Endpoint) {
+			defer wg.Done()
+			r.makeRequest(ctx, ep)
+		}(req.endpoint)
+	}
+}
+
+// replayUnpaced sends the requests in order using the concurrent workers.
+func (r *Runner) replayUnpaced(ctx context.Context, requests []replayRequest) {
+	queue := make(chan Endpoint)
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ep := range queue {
+				r.makeRequest(ctx, ep)
+			}
+		}()
+	}
+
+	defer func() {
+		close(queue)
+		wg.Wait()
+	}()
+
+	for _, req := range requests {
+		select {
+		case <-ctx.Done():
+			return
+		case queue <- req.endpoint:
+		}
+	}
+}
@@ -3,26 +3,35 @@ package runner
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kolosys/helix-stress-test/internal/config"
 	"github.com/kolosys/helix-stress-test/internal/metrics"
+	"github.com/kolosys/helix-stress-test/internal/scenario"
 )
 
 // Endpoint represents a test endpoint.
 type Endpoint struct {
+	Name         string // Template name used for the per-endpoint breakdown (e.g., "GET /items/{id}")
 	Method       string
 	Path         string
+	Headers      map[string]string
 	Body         string
-	HasDynamicID bool // True if path contains {id}, {random_id}, or {delete_id}
+	Generated    *generatedBody // Body generated from a KIND:SIZE spec, sent instead of Body
+	HasDynamicID bool           // True if path contains placeholders such as {id}, {delete_id}, or {int:1-100}
+	HasBodyVars  bool           // True if body contains {seq} or value generators
 }
 
 // ParseEndpoint parses an endpoint string (e.g., "GET:/users/123" or "POST:/items").
@@ -30,6 +39,14 @@ type Endpoint struct {
 // - {id}: Random ID from dataset range (1 to datasetSize) - for GET/PUT operations
 // - {random_id}: Random ID from dataset range - same as {id}
 // - {delete_id}: Random ID from high range (datasetSize-1000 to datasetSize) - for DELETE operations
+// And value generators:
+// - {int:MIN-MAX}: Random integer between MIN and MAX (inclusive)
+// - {string:N}: Random lowercase alphanumeric string of length N
+// - {uuid}: Random version 4 UUID
+// - {seq}: Sequence number, increasing with every use
+// Bodies may contain {seq} and the value generators.
+// A ";body=KIND:SIZE" suffix replaces the body with a generated one of SIZE bytes,
+// encoded as json, form, multipart or binary (e.g., "POST:/upload/binary;body=binary:1MB").
 func ParseEndpoint(s string) (Endpoint, error) {
 	parts := strings.SplitN(s, ":", 2)
 	if len(parts) != 2 {
@@ -37,7 +54,7 @@ func ParseEndpoint(s string) (Endpoint, error) {
 	}
 
 	method := strings.ToUpper(strings.TrimSpace(parts[0]))
-	path := strings.TrimSpace(parts[1])
+	path, bodySpec, hasBody := strings.Cut(strings.TrimSpace(parts[1]), ";body=")
 
 	// Validate method
 	switch method {
@@ -47,55 +64,170 @@ func ParseEndpoint(s string) (Endpoint, error) {
 		return Endpoint{}, fmt.Errorf("invalid HTTP method: %s", method)
 	}
 
-	// Check for dynamic ID placeholders
-	hasDynamicID := strings.Contains(path, "{id}") ||
-		strings.Contains(path, "{random_id}") ||
-		strings.Contains(path, "{delete_id}")
-
 	// Generate default body for POST/PUT/PATCH
 	var body string
 	if method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch {
-		body = `{"name":"test","value":"test"}`
+		body = `{"name":"test","value":"test-{seq}"}`
+	}
+
+	name := method + " " + path
+	var generated *generatedBody
+	if hasBody {
+		if body == "" {
+			return Endpoint{}, fmt.Errorf("invalid endpoint %s: %s requests have no body", s, method)
+		}
+		var err error
+		if generated, err = parseBodySpec(bodySpec); err != nil {
+			return Endpoint{}, fmt.Errorf("invalid endpoint %s: %w", s, err)
+		}
+		body = ""
+		name += " (" + bodySpec + ")"
 	}
 
 	return Endpoint{
+		Name:         name,
 		Method:       method,
 		Path:         path,
 		Body:         body,
-		HasDynamicID: hasDynamicID,
+		Generated:    generated,
+		HasDynamicID: hasPlaceholders(path),
+		HasBodyVars:  hasVars(body),
 	}, nil
 }
 
+// hasPlaceholders reports whether the path contains a dynamic ID placeholder or value generator.
+func hasPlaceholders(path string) bool {
+	return strings.Contains(path, "{id}") ||
+		strings.Contains(path, "{random_id}") ||
+		strings.Contains(path, "{delete_id}") ||
+		hasVars(path)
+}
+
+// hasVars reports whether s contains a sequence placeholder or value generator.
+func hasVars(s string) bool {
+	return strings.Contains(s, "{seq}") || generatorPattern.MatchString(s)
+}
+
 // Runner executes stress tests against a server.
 type Runner struct {
 	cfg         *config.Config
+	baseURL     string
 	client      *http.Client
 	metrics     *metrics.Metrics
 	datasetSize int
 	rng         *rand.Rand
 	rngMu       sync.Mutex
+	scenario    *scenario.Scenario // Workload set by UseScenario
+	trace       *httptrace.ClientTrace
+	tlsConfig   *tls.Config   // TLS configuration of the transport, used by slow clients
+	dial        dialFunc      // Dials the server without counting connections, used by slow clients
+	sent        atomic.Int64  // Requests sent, for connection churn
+	seq         atomic.Int64  // Last value of the {seq} placeholder
+	writes      *writeLog     // Item writes checked after the run (nil without --verify)
+	history     *historyLog   // Item histories checked after the run (nil without --linearizability)
+	warmedUp    chan struct{} // Closed once the warm-up metrics have been set aside
 }
 
-// New creates a new Runner.
-func New(cfg *config.Config, m *metrics.Metrics) *Runner {
+// New creates a new Runner. It fails if the configured TLS CA file cannot be loaded.
+func New(cfg *config.Config, m *metrics.Metrics) (*Runner, error) {
+	// Each virtual user keeps its own connection
+	conns := cfg.Concurrent
+	if cfg.Executor == config.ExecutorVU {
+		conns = max(conns, cfg.VirtualUsers())
+	}
+
+	transport, err := newTransport(cfg, conns, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var writes *writeLog
+	if cfg.Verify {
+		writes = newWriteLog()
+	}
+	var history *historyLog
+	if cfg.Linearizability {
+		history = newHistoryLog()
+	}
+
 	return &Runner{
 		cfg:         cfg,
+		baseURL:     cfg.BaseURL(),
 		datasetSize: cfg.DatasetSize,
-		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:         rand.New(rand.NewSource(cfg.Seed)),
 		client: &http.Client{
-			Timeout: cfg.Timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        cfg.Concurrent * 2,
-				MaxIdleConnsPerHost: cfg.Concurrent,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   cfg.Timeout,
+			Transport: transport,
 		},
-		metrics: m,
-	}
+		metrics:   m,
+		trace:     connTrace(m),
+		tlsConfig: transport.TLSClientConfig,
+		dial:      newDialer(cfg),
+		writes:    writes,
+		history:   history,
+	}, nil
+}
+
+// UseScenario makes the runner send the given scenario instead of the configured
+// endpoints or scenario source. Distributed agents use it to run the scenario the
+// coordinator sent.
+func (r *Runner) UseScenario(s *scenario.Scenario) {
+	r.scenario = s
 }
 
 // Run executes the stress test based on the configured test type.
+// With a warm-up period the test runs for the warm-up plus the test duration, and
+// the metrics collected during the warm-up are set aside when it ends. Slow clients
+// run alongside the test until it ends. With an admin token, the dataset is
+// snapshotted (and reseeded unless disabled) before the run and snapshotted after it.
+// With verification, the item writes acknowledged during the run are then checked
+// against the final dataset, and with the linearizability check, the history of
+// every item is checked offline.
 func (r *Runner) Run(ctx context.Context) error {
+	if err := r.RecordDataset(ctx, "start", r.cfg.Reseed != config.ReseedNone); err != nil {
+		return fmt.Errorf("failed to prepare dataset: %w", err)
+	}
+	if err := r.startVerification(ctx); err != nil {
+		return fmt.Errorf("failed to prepare dataset: %w", err)
+	}
+	if r.history != nil {
+		r.history.begin()
+		defer r.checkHistories()
+	}
+	defer func() {
+		if ctx.Err() == nil {
+			_ = r.RecordDataset(ctx, "end", false)
+			r.verify(ctx)
+		}
+	}()
+
+	// Spikes and slow clients start from the end of the warm-up, once its metrics
+	// are set aside, so the phases they mark aren't reset with them
+	r.warmedUp = make(chan struct{})
+	if r.cfg.Warmup > 0 {
+		warmup := time.AfterFunc(r.cfg.Warmup, func() {
+			r.metrics.EndWarmup()
+			close(r.warmedUp)
+		})
+		defer warmup.Stop()
+	} else {
+		close(r.warmedUp)
+	}
+
+	if r.cfg.SlowClients > 0 {
+		slowCtx, slowCancel := context.WithTimeout(ctx, r.cfg.Warmup+r.cfg.Duration)
+		var slowWg sync.WaitGroup
+		slowWg.Add(1)
+		go func() {
+			defer slowWg.Done()
+			r.runSlowClients(slowCtx)
+		}()
+		defer func() {
+			slowCancel()
+			slowWg.Wait()
+		}()
+	}
+
 	switch r.cfg.TestType {
 	case config.TestTypeLoad:
 		return r.runLoadTest(ctx)
@@ -103,6 +235,8 @@ func (r *Runner) Run(ctx context.Context) error {
 		return r.runSpikeTest(ctx)
 	case config.TestTypeEndurance:
 		return r.runEnduranceTest(ctx)
+	case config.TestTypeReplay:
+		return r.runReplayTest(ctx)
 	default:
 		return fmt.Errorf("unknown test type: %s", r.cfg.TestType)
 	}
@@ -110,29 +244,18 @@ func (r *Runner) Run(ctx context.Context) error {
 
 // runLoadTest runs a sustained load test.
 func (r *Runner) runLoadTest(ctx context.Context) error {
-	endpoints, err := r.parseEndpoints()
+	endpoints, flows, err := r.parseWorkload()
 	if err != nil {
 		return fmt.Errorf("failed to parse endpoints: %w", err)
 	}
 
-	// Calculate request interval
-	interval := time.Second / time.Duration(r.cfg.TargetRPS)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
 	// Start worker goroutines
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Warmup+r.cfg.Duration)
 	defer cancel()
 
 	// Start concurrent workers
-	for i := 0; i < r.cfg.Concurrent; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			r.worker(ctx, endpoints, ticker.C)
-		}()
-	}
+	r.startWorkers(ctx, &wg, endpoints, flows)
 
 	wg.Wait()
 	return nil
@@ -140,128 +263,86 @@ func (r *Runner) runLoadTest(ctx context.Context) error {
 
 // runSpikeTest runs a spike test with sudden bursts.
 func (r *Runner) runSpikeTest(ctx context.Context) error {
-	endpoints, err := r.parseEndpoints()
+	endpoints, flows, err := r.parseWorkload()
 	if err != nil {
 		return fmt.Errorf("failed to parse endpoints: %w", err)
 	}
 
-	// Run baseline load
-	baselineInterval := time.Second / time.Duration(r.cfg.TargetRPS)
-	baselineTicker := time.NewTicker(baselineInterval)
-	defer baselineTicker.Stop()
-
-	ctx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Warmup+r.cfg.Duration)
 	defer cancel()
 
 	var wg sync.WaitGroup
 
 	// Start baseline workers
-	for i := 0; i < r.cfg.Concurrent; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			r.worker(ctx, endpoints, baselineTicker.C)
-		}()
-	}
+	r.startWorkers(ctx, &wg, endpoints, flows)
 
-	// Start spike goroutine
+	// Start spike goroutine; spike offsets are measured from the end of the warm-up
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		r.runSpikes(ctx, endpoints)
+		if r.waitWarmup(ctx) {
+			r.runSpikes(ctx, endpoints)
+		}
 	}()
 
 	wg.Wait()
 	return nil
 }
 
-// runSpikes runs spike bursts during the test.
-func (r *Runner) runSpikes(ctx context.Context, endpoints []Endpoint) {
-	if len(endpoints) == 0 {
-		return
-	}
-
-	// Run spikes periodically
-	ticker := time.NewTicker(r.cfg.SpikeDuration * 2)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			// Burst of requests at spike RPS
-			spikeInterval := time.Second / time.Duration(r.cfg.SpikeRPS)
-			spikeTicker := time.NewTicker(spikeInterval)
-			spikeCtx, spikeCancel := context.WithTimeout(ctx, r.cfg.SpikeDuration)
-
-			var spikeWg sync.WaitGroup
-			for i := 0; i < r.cfg.Concurrent*5; i++ {
-				spikeWg.Add(1)
-				go func(idx int) {
-					defer spikeWg.Done()
-					for {
-						select {
-						case <-spikeCtx.Done():
-							return
-						case <-spikeTicker.C:
-							ep := endpoints[idx%len(endpoints)]
-							r.makeRequest(spikeCtx, ep)
-						}
-					}
-				}(i)
-			}
-
-			spikeWg.Wait()
-			spikeCancel()
-			spikeTicker.Stop()
-		}
+// waitWarmup waits until the warm-up has ended and its metrics have been set
+// aside. It returns false if the context is done first.
+func (r *Runner) waitWarmup(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-r.warmedUp:
+		return true
 	}
 }
 
 // runEnduranceTest runs a long-running test to detect memory leaks.
 func (r *Runner) runEnduranceTest(ctx context.Context) error {
-	endpoints, err := r.parseEndpoints()
+	endpoints, flows, err := r.parseWorkload()
 	if err != nil {
 		return fmt.Errorf("failed to parse endpoints: %w", err)
 	}
 
-	interval := time.Second / time.Duration(r.cfg.TargetRPS)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Warmup+r.cfg.Duration)
 	defer cancel()
 
-	for i := 0; i < r.cfg.Concurrent; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			r.worker(ctx, endpoints, ticker.C)
-		}()
-	}
+	r.startWorkers(ctx, &wg, endpoints, flows)
 
 	wg.Wait()
 	return nil
 }
 
-// worker runs requests in a loop until context is canceled.
-func (r *Runner) worker(ctx context.Context, endpoints []Endpoint, ticker <-chan time.Time) {
-	index := 0
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker:
-			if len(endpoints) == 0 {
-				continue
-			}
-			ep := endpoints[index%len(endpoints)]
-			index++
-			r.makeRequest(ctx, ep)
+// startWorkers starts the workers of the configured executor. With the vu executor,
+// or when the workload contains flows, each virtual user runs its own loop with
+// think time and pacing; otherwise every arrival of the shared rate sends the next
+// endpoint, with at most --concurrent requests in flight.
+func (r *Runner) startWorkers(ctx context.Context, wg *sync.WaitGroup, endpoints []Endpoint, flows []Flow) {
+	if r.cfg.Executor == config.ExecutorVU || len(flows) > 0 {
+		for i := 0; i < r.cfg.VirtualUsers(); i++ {
+			wg.Add(1)
+			go func(idx int) {
+				defer wg.Done()
+				r.virtualUser(ctx, endpoints, flows, idx)
+			}(i)
 		}
+		return
 	}
+
+	if len(endpoints) == 0 {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.dispatchArrivals(ctx, r.cfg.TargetRPS, r.cfg.Concurrent, nil, func(ctx context.Context, n int) {
+			r.makeRequest(ctx, endpoints[n%len(endpoints)])
+		})
+	}()
 }
 
 // getRandomID returns a random ID from the safe range for GET/PUT operations.
@@ -270,7 +351,7 @@ func (r *Runner) worker(ctx context.Context, endpoints []Endpoint, ticker <-chan
 func (r *Runner) getRandomID() int {
 	r.rngMu.Lock()
 	defer r.rngMu.Unlock()
-	
+
 	if r.datasetSize <= 1000 {
 		// If dataset is small, use full range
 		if r.datasetSize <= 0 {
@@ -288,7 +369,7 @@ func (r *Runner) getRandomID() int {
 func (r *Runner) getDeleteID() int {
 	r.rngMu.Lock()
 	defer r.rngMu.Unlock()
-	
+
 	if r.datasetSize <= 1000 {
 		// If dataset is small, use the last item
 		if r.datasetSize <= 0 {
@@ -312,9 +393,59 @@ func (r *Runner) resolvePath(path string) string {
 		path = strings.ReplaceAll(path, "{id}", strconv.Itoa(id))
 		path = strings.ReplaceAll(path, "{random_id}", strconv.Itoa(id))
 	}
+	if strings.Contains(path, "{") {
+		path = r.resolveVars(path)
+	}
 	return path
 }
 
+// resolveVars replaces sequence placeholders and value generators in s.
+func (r *Runner) resolveVars(s string) string {
+	for strings.Contains(s, "{seq}") {
+		s = strings.Replace(s, "{seq}", strconv.FormatInt(r.seq.Add(1), 10), 1)
+	}
+	return generatorPattern.ReplaceAllStringFunc(s, r.generateValue)
+}
+
+// generatorPattern matches value generator placeholders.
+var generatorPattern = regexp.MustCompile(`\{(?:int:-?[0-9]+--?[0-9]+|string:[0-9]+|uuid)\}`)
+
+// generateValue returns a random value for a value generator placeholder.
+func (r *Runner) generateValue(placeholder string) string {
+	spec := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{"), "}")
+	kind, arg, _ := strings.Cut(spec, ":")
+
+	r.rngMu.Lock()
+	defer r.rngMu.Unlock()
+
+	switch kind {
+	case "int":
+		// Split on the dash separating the bounds, allowing a negative minimum.
+		sep := strings.Index(arg[1:], "-") + 1
+		min, _ := strconv.Atoi(arg[:sep])
+		max, _ := strconv.Atoi(arg[sep+1:])
+		if max < min {
+			return strconv.Itoa(min)
+		}
+		return strconv.Itoa(min + r.rng.Intn(max-min+1))
+	case "string":
+		const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+		n, _ := strconv.Atoi(arg)
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = alphabet[r.rng.Intn(len(alphabet))]
+		}
+		return string(b)
+	case "uuid":
+		var b [16]byte
+		r.rng.Read(b[:])
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+	return placeholder
+}
+
 // makeRequest makes a single HTTP request and records metrics.
 func (r *Runner) makeRequest(ctx context.Context, ep Endpoint) {
 	start := time.Now()
@@ -325,54 +456,144 @@ func (r *Runner) makeRequest(ctx context.Context, ep Endpoint) {
 		path = r.resolvePath(ep.Path)
 	}
 
-	// Construct URL - handle both ":8080" and "localhost:8080" formats
-	addr := r.cfg.ServerAddr
-	if strings.HasPrefix(addr, ":") {
-		addr = "localhost" + addr
-	}
-	url := "http://" + addr + path
+	url := r.baseURL + path
 
+	sentBody := ep.Body
+	if ep.HasBodyVars {
+		sentBody = r.resolveVars(ep.Body)
+	}
 	var body io.Reader
-	if ep.Body != "" {
-		body = bytes.NewBufferString(ep.Body)
+	contentType := "application/json"
+	switch {
+	case ep.Generated != nil:
+		body, contentType = bytes.NewReader(ep.Generated.data), ep.Generated.contentType
+	case sentBody != "":
+		body = bytes.NewBufferString(sentBody)
+	}
+
+	// Item requests are logged for the post-run verification and linearizability check
+	var op itemOp
+	var id int
+	if r.writes != nil || r.history != nil {
+		op, id = classifyItemRequest(ep.Method, path)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, ep.Method, url, body)
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, r.trace), ep.Method, url, body)
 	if err != nil {
-		r.metrics.RecordError(0)
+		r.metrics.RecordError(time.Since(start), 0)
 		return
 	}
 
+	// Close the connection after every Nth request so the next one dials a new connection
+	if r.cfg.ConnChurn > 0 && r.sent.Add(1)%int64(r.cfg.ConnChurn) == 0 {
+		req.Close = true
+	}
+
 	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Type", contentType)
 	}
+	setHeaders(req, ep.Headers)
 
 	resp, err := r.client.Do(req)
 	latency := time.Since(start)
 
 	if err != nil {
-		r.metrics.RecordError(0)
+		r.metrics.RecordError(latency, 0)
+		r.metrics.Endpoint(ep.Name).RecordError(latency, 0)
+		r.logItemRequest(op, id, start, sentBody, 0, nil)
 		return
 	}
 	defer resp.Body.Close()
 
-	// Read response body (discard it)
-	_, _ = io.Copy(io.Discard, resp.Body)
+	// Read response body (read streams event by event, and discard the body unless it
+	// holds the item of a logged request); a truncated body fails the request
+	var respBody []byte
+	switch {
+	case isStream(resp):
+		err = r.readStream(ep.Name, resp, start)
+	case op != 0 && op != opDelete:
+		respBody, err = io.ReadAll(resp.Body)
+	default:
+		_, err = io.Copy(io.Discard, resp.Body)
+	}
+	if err != nil {
+		elapsed := time.Since(start)
+		r.metrics.RecordError(elapsed, 0)
+		r.metrics.Endpoint(ep.Name).RecordError(elapsed, 0)
+		r.logItemRequest(op, id, start, sentBody, 0, nil)
+		return
+	}
 
 	r.metrics.RecordRequest(latency, resp.StatusCode)
+	r.metrics.Endpoint(ep.Name).RecordRequest(latency, resp.StatusCode)
+	r.metrics.RecordProtocol(resp.Proto)
+	r.logItemRequest(op, id, start, sentBody, resp.StatusCode, respBody)
+}
+
+// hopHeaders are the hop-by-hop headers, which describe a single connection and
+// are managed by the transport, and Content-Length, which is set from the body.
+var hopHeaders = map[string]bool{
+	"Connection":          true,
+	"Content-Length":      true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
 }
 
-// parseEndpoints parses all endpoint strings.
-func (r *Runner) parseEndpoints() ([]Endpoint, error) {
+// setHeaders sets the headers of an endpoint on req. Host sets the requested host,
+// and hop-by-hop headers recorded by a proxy are skipped.
+func setHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		key = http.CanonicalHeaderKey(key)
+		switch {
+		case key == "Host":
+			req.Host = value
+		case hopHeaders[key]:
+			// Skipped
+		default:
+			req.Header.Set(key, value)
+		}
+	}
+}
+
+// parseWorkload returns the endpoints and flows from the scenario set by UseScenario
+// or the configured scenario, OpenAPI document or HAR file, otherwise it parses all
+// endpoint strings.
+func (r *Runner) parseWorkload() ([]Endpoint, []Flow, error) {
+	s := r.scenario
+	if s == nil && (r.cfg.ScenarioFile != "" || r.cfg.OpenAPIFile != "" || r.cfg.HARFile != "") {
+		var err error
+		if s, err = LoadScenario(r.cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if s != nil {
+		endpoints, err := scenarioEndpoints(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		flows, err := scenarioFlows(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return endpoints, flows, nil
+	}
+
 	endpoints := make([]Endpoint, 0, len(r.cfg.Endpoints))
 	for _, s := range r.cfg.Endpoints {
 		ep, err := ParseEndpoint(s)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		endpoints = append(endpoints, ep)
 	}
-	return endpoints, nil
+	return endpoints, nil, nil
 }
 
 // GenerateTestData generates test data for POST/PUT requests.
@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/har"
+	"github.com/kolosys/helix-stress-test/internal/openapi"
+	"github.com/kolosys/helix-stress-test/internal/scenario"
+)
+
+// LoadScenario returns the scenario configured by --scenario, or one generated
+// from the OpenAPI document configured by --openapi or the HAR file configured by --har.
+func LoadScenario(cfg *config.Config) (*scenario.Scenario, error) {
+	if cfg.ScenarioFile != "" {
+		return scenario.Load(cfg.ScenarioFile)
+	}
+
+	if cfg.OpenAPIFile != "" {
+		doc, err := openapi.Load(cfg.OpenAPIFile)
+		if err != nil {
+			return nil, err
+		}
+		s := doc.Generate(cfg.OpenAPIFile)
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("OpenAPI document %s: %w", cfg.OpenAPIFile, err)
+		}
+		return s, nil
+	}
+
+	if cfg.HARFile != "" {
+		f, err := har.Load(cfg.HARFile)
+		if err != nil {
+			return nil, err
+		}
+		s, err := f.Import(cfg.HARFile, har.Options{
+			Hosts:        cfg.HARHosts,
+			ContentTypes: cfg.HARContentTypes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("HAR file %s: %w", cfg.HARFile, err)
+		}
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("no scenario, OpenAPI document, or HAR file configured")
+}
+
+// scenarioEndpoints converts the scenario endpoints into runner endpoints.
+// A scenario that only contains flows yields the endpoints of all flow steps,
+// which are used where a test needs a flat endpoint list (e.g., spike bursts).
+func scenarioEndpoints(s *scenario.Scenario) ([]Endpoint, error) {
+	endpoints := make([]Endpoint, 0, len(s.Endpoints))
+	for _, se := range s.Endpoints {
+		ep, err := scenarioEndpoint(se)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	if len(endpoints) == 0 {
+		for _, flow := range s.Flows {
+			for _, step := range flow.Steps {
+				ep, err := scenarioEndpoint(step.Endpoint)
+				if err != nil {
+					return nil, err
+				}
+				endpoints = append(endpoints, ep)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// scenarioFlows converts the scenario flows into runner flows.
+func scenarioFlows(s *scenario.Scenario) ([]Flow, error) {
+	flows := make([]Flow, 0, len(s.Flows))
+	for i, sf := range s.Flows {
+		flow := Flow{Name: sf.Name, Steps: make([]FlowStep, 0, len(sf.Steps))}
+		if flow.Name == "" {
+			flow.Name = fmt.Sprintf("flow-%d", i+1)
+		}
+		for _, step := range sf.Steps {
+			ep, err := scenarioEndpoint(step.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			flow.Steps = append(flow.Steps, FlowStep{
+				Endpoint:  ep,
+				ThinkTime: time.Duration(step.ThinkTimeMS) * time.Millisecond,
+			})
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// scenarioEndpoint converts a scenario endpoint into a runner endpoint.
+func scenarioEndpoint(se scenario.Endpoint) (Endpoint, error) {
+	method := strings.ToUpper(se.Method)
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		// Valid
+	default:
+		return Endpoint{}, fmt.Errorf("invalid HTTP method in scenario: %s", se.Method)
+	}
+
+	name := se.Name
+	if name == "" {
+		name = method + " " + se.Path
+	}
+
+	body := se.Body
+	var generated *generatedBody
+	if se.BodyGenerator != "" {
+		var err error
+		if generated, err = parseBodySpec(se.BodyGenerator); err != nil {
+			return Endpoint{}, fmt.Errorf("invalid body generator in scenario: %w", err)
+		}
+		body = ""
+	}
+
+	return Endpoint{
+		Name:         name,
+		Method:       method,
+		Path:         se.Path,
+		Headers:      se.Headers,
+		Body:         body,
+		Generated:    generated,
+		HasDynamicID: hasPlaceholders(se.Path),
+		HasBodyVars:  hasVars(body),
+	}, nil
+}
@@ -0,0 +1,304 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// slowClientsPhase is the phase during which the slow clients are connected.
+const slowClientsPhase = "slow clients"
+
+// slowBody is the request body trickled by slow upload clients.
+const slowBody = `{"name":"slow-client","value":"` + "slow-client-upload-slow-client-upload-slow-client-upload" + `"}`
+
+// slowReadPath is the response read by slow read clients: about 500KB, far more
+// than the socket buffers hold, so the server's writes block on the reader.
+const slowReadPath = "/large?items=10000"
+
+// slowReadBuffer is the receive buffer of slow read connections.
+const slowReadBuffer = 1024
+
+// runSlowClients holds the configured slow client connections alongside the load
+// from SlowStart until the end of the test. Each client reconnects whenever its
+// connection ends. Outside spike tests, requests sent before the slow clients
+// connect form the baseline phase, so the report shows their impact on normal traffic.
+func (r *Runner) runSlowClients(ctx context.Context) {
+	if !r.waitWarmup(ctx) {
+		return
+	}
+
+	trackPhases := r.cfg.TestType != config.TestTypeSpike
+	if trackPhases {
+		r.metrics.SetPhase(metrics.PhaseBaseline)
+		defer r.metrics.SetPhase("")
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	if !sleepContext(ctx, timer, r.cfg.SlowStart) {
+		return
+	}
+	if trackPhases {
+		r.metrics.SetPhase(slowClientsPhase)
+		r.phaseBoundary(ctx, slowClientsPhase)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.SlowClients; i++ {
+		wg.Add(1)
+		go func(mode config.SlowMode) {
+			defer wg.Done()
+			r.slowClient(ctx, mode)
+		}(r.cfg.SlowModes[i%len(r.cfg.SlowModes)])
+	}
+	wg.Wait()
+}
+
+// slowClient opens slow connections of the given mode until the context is done.
+func (r *Runner) slowClient(ctx context.Context, mode config.SlowMode) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for ctx.Err() == nil {
+		start := time.Now()
+		outcome := r.slowConnection(ctx, mode)
+		r.metrics.RecordSlowClient(string(mode), outcome, time.Since(start))
+
+		// Don't hammer a server that refuses connections
+		if outcome == metrics.SlowFailed && !sleepContext(ctx, timer, r.cfg.SlowInterval) {
+			return
+		}
+	}
+}
+
+// slowConnection opens a connection, runs the slow client behaviour on it and
+// returns how it ended.
+func (r *Runner) slowConnection(ctx context.Context, mode config.SlowMode) metrics.SlowOutcome {
+	conn, err := r.dialSlow(ctx, mode)
+	if err != nil {
+		if ctx.Err() != nil {
+			return metrics.SlowHeld
+		}
+		return metrics.SlowFailed
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes when the test ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	switch mode {
+	case config.SlowUpload:
+		err = r.slowUpload(ctx, conn)
+	case config.SlowRead:
+		err = r.slowRead(ctx, conn)
+	case config.SlowIdle:
+		err = waitClosed(conn)
+	case config.SlowHeaders:
+		err = r.slowHeaders(ctx, conn)
+	default:
+		err = fmt.Errorf("unknown slow client mode: %s", mode)
+	}
+
+	if ctx.Err() != nil {
+		return metrics.SlowHeld
+	}
+	if err != nil {
+		return metrics.SlowClosedByServer
+	}
+	return metrics.SlowCompleted
+}
+
+// dialSlow opens a raw connection to the server, or its Unix domain socket,
+// completing the TLS handshake for https targets. Slow clients always speak HTTP/1.1.
+// Slow read connections get a small receive buffer, set before the handshake since
+// a TLS connection doesn't expose it.
+func (r *Runner) dialSlow(ctx context.Context, mode config.SlowMode) (net.Conn, error) {
+	target, err := url.Parse(r.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := r.dial(ctx, "tcp", net.JoinHostPort(target.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if mode == config.SlowRead {
+		// A small receive buffer makes the server's writes block sooner
+		if c, ok := conn.(interface{ SetReadBuffer(int) error }); ok {
+			_ = c.SetReadBuffer(slowReadBuffer)
+		}
+	}
+	if target.Scheme != "https" {
+		return conn, nil
+	}
+
+	tlsConfig := r.tlsConfig.Clone()
+	tlsConfig.ServerName = target.Hostname()
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// slowRequestHead returns the request line and headers of a slow client request
+// for the path relative to the base URL.
+func (r *Runner) slowRequestHead(method, path string, headers ...string) string {
+	target, _ := url.Parse(r.baseURL)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s%s HTTP/1.1\r\n", method, target.EscapedPath(), path)
+	fmt.Fprintf(&b, "Host: %s\r\n", target.Host)
+	b.WriteString("User-Agent: helix-stress-test/slow-client\r\n")
+	for _, h := range headers {
+		b.WriteString(h + "\r\n")
+	}
+	return b.String()
+}
+
+// slowUpload sends a POST request whose body is written one byte per interval and
+// reads the response.
+func (r *Runner) slowUpload(ctx context.Context, conn net.Conn) error {
+	head := r.slowRequestHead(http.MethodPost, "/items",
+		"Content-Type: application/json",
+		fmt.Sprintf("Content-Length: %d", len(slowBody)),
+	) + "\r\n"
+	if _, err := io.WriteString(conn, head); err != nil {
+		return err
+	}
+	if err := r.writeSlowly(ctx, conn, []byte(slowBody)); err != nil {
+		return err
+	}
+	return readResponse(bufio.NewReader(conn))
+}
+
+// slowRead requests a large response and reads it one byte per interval.
+func (r *Runner) slowRead(ctx context.Context, conn net.Conn) error {
+	head := r.slowRequestHead(http.MethodGet, slowReadPath) + "\r\n"
+	if _, err := io.WriteString(conn, head); err != nil {
+		return err
+	}
+	return readResponse(bufio.NewReaderSize(&slowReader{ctx: ctx, r: conn, interval: r.cfg.SlowInterval}, 16))
+}
+
+// slowHeaders sends a request whose headers are written one byte per interval and
+// never completed. It returns when the server closes the connection.
+func (r *Runner) slowHeaders(ctx context.Context, conn net.Conn) error {
+	head := r.slowRequestHead(http.MethodGet, "/")
+	if _, err := io.WriteString(conn, head); err != nil {
+		return err
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- waitClosed(conn) }()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for i := 0; ; i++ {
+		header := fmt.Sprintf("X-Slow-Client-%d: 1\r\n", i)
+		for j := 0; j < len(header); j++ {
+			if _, err := conn.Write([]byte{header[j]}); err != nil {
+				return err
+			}
+			timer.Reset(r.cfg.SlowInterval)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-closed:
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// writeSlowly writes data one byte per interval.
+func (r *Runner) writeSlowly(ctx context.Context, conn net.Conn, data []byte) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for i := range data {
+		if _, err := conn.Write(data[i : i+1]); err != nil {
+			return err
+		}
+		if i < len(data)-1 && !sleepContext(ctx, timer, r.cfg.SlowInterval) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// waitClosed blocks until the server closes the connection or sends data, which a
+// server only does on a connection without a complete request to reject it.
+func waitClosed(conn net.Conn) error {
+	var buf [1]byte
+	_, err := conn.Read(buf[:])
+	if err == nil {
+		err = fmt.Errorf("server responded to an incomplete request")
+	}
+	return err
+}
+
+// readResponse reads and discards a response.
+func readResponse(br *bufio.Reader) error {
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// slowReader reads at most one byte per interval.
+type slowReader struct {
+	ctx      context.Context
+	r        io.Reader
+	interval time.Duration
+	timer    *time.Timer
+}
+
+// Read reads one byte after waiting for the interval.
+func (s *slowReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if s.timer == nil {
+		s.timer = time.NewTimer(s.interval)
+	} else {
+		s.timer.Reset(s.interval)
+	}
+	select {
+	case <-s.ctx.Done():
+		s.timer.Stop()
+		return 0, s.ctx.Err()
+	case <-s.timer.C:
+	}
+	return s.r.Read(p[:1])
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// runSpikes runs the configured spike schedule on top of the baseline load.
+// Each spike starts at its offset from the start of the test and sends additional
+// requests following its shape, and is recorded so its recovery can be measured.
+// Requests are tagged with the baseline, spike N and recovery N phases.
+func (r *Runner) runSpikes(ctx context.Context, endpoints []Endpoint) {
+	if len(endpoints) == 0 {
+		return
+	}
+
+	r.metrics.SetPhase(metrics.PhaseBaseline)
+	defer r.metrics.SetPhase("")
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, spike := range r.cfg.Spikes() {
+		wg.Add(1)
+		go func(index int, spike config.Spike) {
+			defer wg.Done()
+
+			timer := time.NewTimer(0)
+			defer timer.Stop()
+			<-timer.C
+			if !sleepContext(ctx, timer, time.Until(start.Add(spike.Offset))) {
+				return
+			}
+			r.runSpike(ctx, endpoints, spike, index)
+		}(i+1, spike)
+	}
+	wg.Wait()
+}
+
+// runSpike sends the requests of a single spike. After the spike the recovery
+// phase lasts as long as the spike itself before returning to the baseline phase,
+// unless another spike starts first.
+func (r *Runner) runSpike(ctx context.Context, endpoints []Endpoint, spike config.Spike, index int) {
+	spikePhase := fmt.Sprintf("spike %d", index)
+	recoveryPhase := fmt.Sprintf("recovery %d", index)
+
+	r.metrics.SetPhase(spikePhase)
+	r.phaseBoundary(ctx, spikePhase)
+	r.sendSpike(ctx, endpoints, spike)
+	if !r.metrics.SwitchPhase(spikePhase, recoveryPhase) {
+		return
+	}
+	r.phaseBoundary(ctx, recoveryPhase)
+
+	timer := time.NewTimer(spike.Duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+		if r.metrics.SwitchPhase(recoveryPhase, metrics.PhaseBaseline) {
+			r.phaseBoundary(ctx, metrics.PhaseBaseline)
+		}
+	}
+}
+
+// sendSpike sends the additional requests of a spike following its shape.
+func (r *Runner) sendSpike(ctx context.Context, endpoints []Endpoint, spike config.Spike) {
+	spikeCtx, spikeCancel := context.WithTimeout(ctx, spike.Duration)
+	defer spikeCancel()
+
+	end := r.metrics.RecordSpikeStart(spike.PeakRPS, string(spike.Shape))
+	defer end()
+
+	r.dispatchArrivals(spikeCtx, spike.PeakRPS, r.cfg.Concurrent*5, spikeShape(spike), func(ctx context.Context, n int) {
+		r.makeRequest(ctx, endpoints[n%len(endpoints)])
+	})
+}
+
+// spikeShape returns the fraction of the peak rate at each point of the spike.
+// Triangle spikes rise linearly to the peak at the midpoint and fall back; exponential
+// spikes grow and decay geometrically from one request per second to the peak.
+func spikeShape(spike config.Spike) func(elapsed time.Duration) float64 {
+	// position returns the distance from the midpoint: 1 at the edges, 0 at the peak
+	position := func(elapsed time.Duration) float64 {
+		x := float64(elapsed) / float64(spike.Duration)
+		return math.Min(1, math.Abs(2*x-1))
+	}
+
+	switch spike.Shape {
+	case config.SpikeTriangle:
+		return func(elapsed time.Duration) float64 {
+			return 1 - position(elapsed)
+		}
+	case config.SpikeExponential:
+		return func(elapsed time.Duration) float64 {
+			return math.Pow(float64(spike.PeakRPS), -position(elapsed))
+		}
+	default:
+		return nil
+	}
+}
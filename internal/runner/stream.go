@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Content types of the streamed responses the runner reads event by event.
+const (
+	contentTypeSSE    = "text/event-stream"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// isStream reports whether resp is a stream of server-sent events or of
+// newline-delimited JSON.
+func isStream(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == contentTypeSSE || mediaType == contentTypeNDJSON
+}
+
+// readStream reads a streamed response to its end and records the time to its
+// first event, its events and its duration since start. Server-sent events end
+// with a blank line, and comments are not events; in other streams every line
+// is an event. It returns the error that cut the stream short, if any.
+func (r *Runner) readStream(endpoint string, resp *http.Response, start time.Time) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	sse := mediaType == contentTypeSSE
+
+	var events, size int64
+	var firstEvent time.Duration
+	event := func() {
+		if events == 0 {
+			firstEvent = time.Since(start)
+		}
+		events++
+	}
+
+	br := bufio.NewReader(resp.Body)
+	var inLine, blank, comment, fields bool
+	var err error
+	for {
+		var chunk []byte
+		chunk, err = br.ReadSlice('\n')
+		size += int64(len(chunk))
+		if len(chunk) > 0 && !inLine {
+			// A new line starts
+			inLine = true
+			blank = chunk[0] == '\n' || chunk[0] == '\r'
+			comment = chunk[0] == ':'
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// The line goes on past the buffer
+			blank = false
+			continue
+		}
+
+		if inLine {
+			inLine = false
+			switch {
+			case !sse:
+				if !blank {
+					event()
+				}
+			case blank:
+				if fields {
+					event()
+				}
+				fields = false
+			case !comment:
+				fields = true
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	complete := errors.Is(err, io.EOF)
+	r.metrics.RecordStream(endpoint, events, size, firstEvent, time.Since(start), complete)
+	if complete {
+		return nil
+	}
+	return err
+}
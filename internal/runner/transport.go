@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// newTransport creates the HTTP transport for the configured protocol, TLS trust and
+// connection management. conns is the number of connections kept idle per host.
+// Connections are counted in m as they are opened and closed.
+func newTransport(cfg *config.Config, conns int, m *metrics.Metrics) (*http.Transport, error) {
+	transport := &http.Transport{
+		DialContext:         countingDialer(newDialer(cfg), m),
+		MaxIdleConns:        conns * 2,
+		MaxIdleConnsPerHost: conns,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		DisableKeepAlives:   !cfg.KeepAlive,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.TLSCA != "" || len(cfg.TLSRoots) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if cfg.TLSCA != "" {
+			pem, err := os.ReadFile(cfg.TLSCA)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in TLS CA file %s", cfg.TLSCA)
+			}
+		}
+		if len(cfg.TLSRoots) > 0 && !pool.AppendCertsFromPEM(cfg.TLSRoots) {
+			return nil, fmt.Errorf("no certificates found in the trusted certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	var protocols http.Protocols
+	switch cfg.Protocol {
+	case config.ProtocolH2:
+		protocols.SetHTTP2(true)
+	case config.ProtocolH2C:
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+	}
+	transport.Protocols = &protocols
+
+	return transport, nil
+}
+
+// dialFunc dials a network connection.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newDialer returns the function connections to the server are dialed with. For a
+// Unix domain socket server address every connection dials the socket, whatever
+// the host of the request URL.
+func newDialer(cfg *config.Config) dialFunc {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if path := cfg.UnixSocket(); path != "" {
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+	}
+	return dialer.DialContext
+}
+
+// countingDialer wraps dial so every connection it opens is recorded in m when
+// opened and when closed.
+func countingDialer(dial dialFunc, m *metrics.Metrics) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		m.RecordConnOpened()
+		return &countedConn{Conn: conn, metrics: m}, nil
+	}
+}
+
+// countedConn records its closing in the metrics once.
+type countedConn struct {
+	net.Conn
+	metrics *metrics.Metrics
+	once    sync.Once
+}
+
+// Close closes the connection.
+func (c *countedConn) Close() error {
+	c.once.Do(c.metrics.RecordConnClosed)
+	return c.Conn.Close()
+}
+
+// connTrace returns a client trace that records requests sent over reused connections.
+func connTrace(m *metrics.Metrics) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				m.RecordConnReused()
+			}
+		},
+	}
+}
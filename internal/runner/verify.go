@@ -0,0 +1,333 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kolosys/helix-stress-test/internal/metrics"
+)
+
+// storeDump is the response of the admin dump route.
+type storeDump struct {
+	Items  []item `json:"items"`
+	NextID int    `json:"next_id"`
+}
+
+// write is an item write sent during the run.
+type write struct {
+	kind       itemOp
+	start, end time.Time
+	acked      bool // The server acknowledged it
+	known      bool // The name and value are known
+	name       string
+	value      string
+}
+
+// writeLog records the item writes sent during the run, and checks them against
+// the final dataset. Writes are checked against a baseline dump taken when the
+// log starts; writes sent before it are treated as unacknowledged, since they
+// may or may not be reflected in it.
+type writeLog struct {
+	mu       sync.Mutex
+	writes   map[int][]write // Writes by item ID
+	unplaced []write         // Unacknowledged creates, whose ID is unknown
+	baseline map[int]item    // Dataset when the log started
+	since    time.Time       // When the baseline was taken
+	err      error           // Why the log can't be checked
+}
+
+// newWriteLog creates an empty write log.
+func newWriteLog() *writeLog {
+	return &writeLog{writes: make(map[int][]write)}
+}
+
+// clear drops the logged writes, before the dataset is reseeded.
+func (l *writeLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.writes = make(map[int][]write)
+	l.unplaced = nil
+}
+
+// start sets the baseline the writes sent from now on are checked against.
+func (l *writeLog) start(dump storeDump) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.baseline = make(map[int]item, len(dump.Items))
+	for _, it := range dump.Items {
+		l.baseline[it.ID] = it
+	}
+	l.since = time.Now()
+}
+
+// fail makes the check report err instead of checking the writes.
+func (l *writeLog) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// record logs a write of item id; id is 0 for unacknowledged creates.
+func (l *writeLog) record(id int, w write) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id == 0 {
+		l.unplaced = append(l.unplaced, w)
+		return
+	}
+	l.writes[id] = append(l.writes[id], w)
+}
+
+// acked reports whether w is known to have been applied after the baseline.
+// Callers hold the lock.
+func (l *writeLog) acked(w write) bool {
+	return w.acked && !w.start.Before(l.since)
+}
+
+// logWrite records an item write in the write log. status is 0 if no complete
+// response was received, and body is the response body of creates and updates.
+// Writes the server rejected are not logged.
+func (r *Runner) logWrite(kind itemOp, id int, start time.Time, sent string, status int, body []byte) {
+	if r.writes == nil || kind == opRead {
+		return
+	}
+
+	w := write{kind: kind, start: start, end: time.Now()}
+	var it item
+	if err := json.Unmarshal([]byte(sent), &it); err == nil {
+		w.known, w.name, w.value = true, it.Name, it.Value
+	}
+
+	switch {
+	case status >= 200 && status < 300:
+		w.acked = true
+		if kind == opCreate || kind == opUpdate {
+			// The response holds what the server stored
+			var stored item
+			if err := json.Unmarshal(body, &stored); err != nil || (kind == opCreate && stored.ID == 0) {
+				w.acked = false
+			} else {
+				id, w.known, w.name, w.value = stored.ID, true, stored.Name, stored.Value
+			}
+		}
+	case status >= 400 && status < 500:
+		return
+	}
+	if kind == opCreate && !w.acked {
+		id = 0
+	}
+	r.writes.record(id, w)
+}
+
+// startVerification dumps the dataset as the baseline of the write log.
+func (r *Runner) startVerification(ctx context.Context) error {
+	if r.writes == nil {
+		return nil
+	}
+
+	dump, err := r.adminDump(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to dump dataset: %w", err)
+		r.writes.fail(err)
+		return err
+	}
+	r.writes.start(dump)
+	return nil
+}
+
+// verify dumps the final dataset and records the result of checking the write
+// log against it.
+func (r *Runner) verify(ctx context.Context) {
+	if r.writes == nil {
+		return
+	}
+
+	dump, err := r.adminDump(ctx)
+	if err != nil {
+		r.metrics.SetVerification(metrics.Verification{Error: fmt.Sprintf("failed to dump dataset: %v", err)})
+		return
+	}
+	r.metrics.SetVerification(r.writes.check(dump))
+}
+
+// adminDump returns every item of the server's dataset.
+func (r *Runner) adminDump(ctx context.Context) (storeDump, error) {
+	var dump storeDump
+	err := r.adminRequest(ctx, http.MethodGet, "/admin/dump", &dump)
+	return dump, err
+}
+
+// check compares the final dataset with the logged writes. Writes with an
+// unknown outcome may or may not have been applied, so they never cause a
+// violation, but they can explain the final state of an item.
+func (l *writeLog) check(final storeDump) metrics.Verification {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return metrics.Verification{Error: l.err.Error()}
+	}
+
+	v := metrics.Verification{Items: len(final.Items), Unacknowledged: int64(len(l.unplaced))}
+	for _, writes := range l.writes {
+		for _, w := range writes {
+			switch {
+			case !l.acked(w):
+				v.Unacknowledged++
+			case w.kind == opCreate:
+				v.Creates++
+			case w.kind == opUpdate:
+				v.Updates++
+			case w.kind == opDelete:
+				v.Deletes++
+			}
+		}
+	}
+
+	present := make(map[int]item, len(final.Items))
+	for _, it := range final.Items {
+		present[it.ID] = it
+	}
+	ids := make([]int, 0, len(present)+len(l.writes))
+	for id := range present {
+		ids = append(ids, id)
+	}
+	for id := range l.writes {
+		if _, ok := present[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	for id := range l.baseline {
+		if _, ok := present[id]; !ok {
+			if _, ok := l.writes[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+
+	var unrecorded []item
+	for _, id := range ids {
+		it, ok := present[id]
+		if !ok {
+			l.checkMissing(&v, id)
+			continue
+		}
+		if _, inBase := l.baseline[id]; !inBase && !l.created(id) {
+			unrecorded = append(unrecorded, it)
+			continue
+		}
+		l.checkItem(&v, it)
+	}
+	l.checkUnrecorded(&v, unrecorded)
+	return v
+}
+
+// created reports whether a create of item id was logged. Callers hold the lock.
+func (l *writeLog) created(id int) bool {
+	return slices.ContainsFunc(l.writes[id], func(w write) bool { return w.kind == opCreate })
+}
+
+// checkMissing checks an item missing from the final dataset was deleted, unless
+// it never existed. Callers hold the lock.
+func (l *writeLog) checkMissing(v *metrics.Verification, id int) {
+	_, existed := l.baseline[id]
+	for _, w := range l.writes[id] {
+		switch {
+		case w.kind == opDelete:
+			return
+		case l.acked(w):
+			existed = true
+		}
+	}
+	if existed {
+		v.Missing++
+		v.AddExample(fmt.Sprintf("item %d is missing, but was never deleted", id))
+	}
+}
+
+// checkItem checks an item of the final dataset isn't deleted and holds the value
+// of a write no acknowledged write followed. Callers hold the lock.
+func (l *writeLog) checkItem(v *metrics.Verification, it item) {
+	writes := l.writes[it.ID]
+	for _, w := range writes {
+		if w.kind == opDelete && l.acked(w) {
+			v.LostDeletes++
+			v.AddExample(fmt.Sprintf("item %d is present after an acknowledged delete", it.ID))
+			return
+		}
+	}
+
+	// Find when the final value was written at the latest
+	var matched, unbounded bool
+	var latest time.Time
+	if base, ok := l.baseline[it.ID]; ok && base == it {
+		matched, latest = true, l.since
+	}
+	for _, w := range writes {
+		if w.kind == opDelete || (w.known && (w.name != it.Name || w.value != it.Value)) {
+			continue
+		}
+		matched = true
+		if !l.acked(w) {
+			// It may have been applied at any time
+			unbounded = true
+		} else if w.end.After(latest) {
+			latest = w.end
+		}
+	}
+
+	switch {
+	case !matched:
+		v.UnknownValues++
+		v.AddExample(fmt.Sprintf("item %d holds %q, which no write sent", it.ID, it.Value))
+	case !unbounded:
+		for _, w := range writes {
+			if w.kind != opDelete && l.acked(w) && w.start.After(latest) {
+				v.LostUpdates++
+				v.AddExample(fmt.Sprintf("item %d holds %q, overwritten by an acknowledged write of %q", it.ID, it.Value, w.value))
+				return
+			}
+		}
+	}
+}
+
+// checkUnrecorded checks the items without a logged create were created by the
+// unacknowledged creates, first matching their values. Callers hold the lock.
+func (l *writeLog) checkUnrecorded(v *metrics.Verification, items []item) {
+	creates := make(map[[2]string]int)
+	for _, w := range l.unplaced {
+		if w.known {
+			creates[[2]string{w.name, w.value}]++
+		}
+	}
+	spare := len(l.unplaced)
+
+	var rest []item
+	for _, it := range items {
+		key := [2]string{it.Name, it.Value}
+		if creates[key] > 0 {
+			creates[key]--
+			spare--
+			continue
+		}
+		rest = append(rest, it)
+	}
+
+	// The others may have been updated since, so any unacknowledged create can explain them
+	for _, it := range rest[min(spare, len(rest)):] {
+		v.Unrecorded++
+		v.AddExample(fmt.Sprintf("item %d is present without a recorded create", it.ID))
+	}
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"time"
+)
+
+// virtualUser runs its own request loop until context is canceled.
+//
+// Each iteration is one pass over the endpoints, or one flow when the workload
+// contains flows (virtual users start at different flows so all of them are
+// exercised concurrently). Before every request except the first of an iteration
+// the user pauses for a think time drawn from the configured distribution; flow
+// steps use their recorded think time when no distribution is configured. If
+// pacing is set, iterations shorter than the pacing interval are padded to it.
+func (r *Runner) virtualUser(ctx context.Context, endpoints []Endpoint, flows []Flow, index int) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for ctx.Err() == nil {
+		start := time.Now()
+
+		if len(flows) > 0 {
+			flow := flows[index%len(flows)]
+			index++
+			for i, step := range flow.Steps {
+				think := step.ThinkTime
+				if r.cfg.ThinkTime.Kind != "" {
+					think = r.thinkTime()
+				}
+				if i > 0 && !sleepContext(ctx, timer, think) {
+					return
+				}
+				r.makeRequest(ctx, step.Endpoint)
+			}
+		} else {
+			if len(endpoints) == 0 {
+				return
+			}
+			for i, ep := range endpoints {
+				if i > 0 && !sleepContext(ctx, timer, r.thinkTime()) {
+					return
+				}
+				r.makeRequest(ctx, ep)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		r.metrics.RecordIteration(time.Since(start))
+
+		// Think between iterations as well, then pad the iteration to the pacing interval.
+		if !sleepContext(ctx, timer, r.thinkTime()) {
+			return
+		}
+		if r.cfg.Pacing > 0 && !sleepContext(ctx, timer, r.cfg.Pacing-time.Since(start)) {
+			return
+		}
+	}
+}
+
+// thinkTime draws a think time from the configured distribution.
+func (r *Runner) thinkTime() time.Duration {
+	tt := r.cfg.ThinkTime
+	switch tt.Kind {
+	case "constant":
+		return tt.Min
+	case "uniform":
+		r.rngMu.Lock()
+		defer r.rngMu.Unlock()
+		return tt.Min + time.Duration(r.rng.Int63n(int64(tt.Max-tt.Min)+1))
+	case "exponential":
+		r.rngMu.Lock()
+		defer r.rngMu.Unlock()
+		return time.Duration(r.rng.ExpFloat64() * float64(tt.Min))
+	}
+	return 0
+}
+
+// sleepContext waits for d using timer, returning false if context is canceled first.
+func sleepContext(ctx context.Context, timer *time.Timer, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer.Reset(d)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,120 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Scenario describes the requests a stress test sends.
+// It can be generated from an OpenAPI document or HAR file, edited by hand and loaded with --scenario.
+//
+// Endpoints are sent by the rate-driven workers. Flows are ordered request sequences
+// replayed by each worker with the recorded think time before every step.
+type Scenario struct {
+	Source    string     `json:"source,omitempty"` // Where the scenario was generated from
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	Flows     []Flow     `json:"flows,omitempty"`
+}
+
+// Endpoint is a single request template in a scenario.
+// Path supports the same placeholders as --endpoints (e.g., {id}, {int:1-100}, {string:8}, {uuid}).
+type Endpoint struct {
+	Name          string            `json:"name,omitempty"`
+	Method        string            `json:"method"`
+	Path          string            `json:"path"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	BodyGenerator string            `json:"body_generator,omitempty"` // Generated body replacing Body, as KIND:SIZE (e.g., "multipart:1MB")
+}
+
+// Flow is an ordered sequence of requests, such as a recorded browser session.
+type Flow struct {
+	Name  string `json:"name,omitempty"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a request in a flow.
+type Step struct {
+	Endpoint
+	ThinkTimeMS int64 `json:"think_time_ms,omitempty"` // Pause before sending the request
+}
+
+// Load reads a scenario file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file: %w", err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// Save writes the scenario to path as indented JSON.
+func (s *Scenario) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scenario: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create scenario directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write scenario file: %w", err)
+	}
+	return nil
+}
+
+// Validate validates the scenario.
+func (s *Scenario) Validate() error {
+	if len(s.Endpoints) == 0 && len(s.Flows) == 0 {
+		return fmt.Errorf("scenario contains no endpoints or flows")
+	}
+
+	for i, ep := range s.Endpoints {
+		if err := ep.validate(); err != nil {
+			return fmt.Errorf("endpoint %d: %w", i, err)
+		}
+	}
+
+	for i, flow := range s.Flows {
+		if len(flow.Steps) == 0 {
+			return fmt.Errorf("flow %d: flow contains no steps", i)
+		}
+		for j, step := range flow.Steps {
+			if err := step.validate(); err != nil {
+				return fmt.Errorf("flow %d step %d: %w", i, j, err)
+			}
+			if step.ThinkTimeMS < 0 {
+				return fmt.Errorf("flow %d step %d: think time cannot be negative", i, j)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate validates a single endpoint.
+func (e Endpoint) validate() error {
+	if e.Method == "" {
+		return fmt.Errorf("method cannot be empty")
+	}
+	if e.Path == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+	return nil
+}
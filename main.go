@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/kolosys/helix-stress-test/internal/config"
+	"github.com/kolosys/helix-stress-test/internal/distributed"
+	"github.com/kolosys/helix-stress-test/internal/matrix"
 	"github.com/kolosys/helix-stress-test/internal/metrics"
 	"github.com/kolosys/helix-stress-test/internal/report"
 	"github.com/kolosys/helix-stress-test/internal/runner"
+	"github.com/kolosys/helix-stress-test/internal/scenario"
 	"github.com/kolosys/helix-stress-test/server"
 )
 
@@ -24,6 +29,55 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Write the generated scenario and exit if requested
+	if cfg.WriteScenario != "" {
+		s, err := runner.LoadScenario(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating scenario: %v\n", err)
+			os.Exit(1)
+		}
+		if err := s.Save(cfg.WriteScenario); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing scenario: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %d endpoints and %d flows to %s\n", len(s.Endpoints), len(s.Flows), cfg.WriteScenario)
+		return
+	}
+
+	// Run as an agent until interrupted
+	if cfg.Mode == config.ModeAgent {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := distributed.Serve(ctx, cfg.AgentListen, cfg.AgentToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Agent error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Guard the admin routes of the embedded server with a token only the runner knows
+	if cfg.EmbeddedServer() && cfg.AdminToken == "" {
+		if cfg.AdminToken, err = server.NewAdminToken(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Run the workload against every middleware combination or server implementation
+	if cfg.TestType == config.TestTypeMatrix || cfg.TestType == config.TestTypeCompare {
+		runMatrix(cfg)
+		return
+	}
+
+	// The coordinator sends the scenario to the agents so they don't need the source files
+	var workload *scenario.Scenario
+	if cfg.Mode == config.ModeCoordinator && (cfg.ScenarioFile != "" || cfg.OpenAPIFile != "" || cfg.HARFile != "") {
+		if workload, err = runner.LoadScenario(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading scenario: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create metrics collector
 	m := metrics.New()
 
@@ -35,47 +89,132 @@ func main() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
-	// Get log file path before starting server
-	logFilePath := server.GetLogFilePath(string(cfg.TestType))
-
-	// Start server in background
+	// Start the embedded server in background unless testing an external server
+	var logFilePath string
 	serverCtx, serverCancel := context.WithCancel(ctx)
 	var serverWg sync.WaitGroup
 	var logCleanup func() error
-	serverWg.Add(1)
-	go func() {
-		defer serverWg.Done()
-		_, cleanup, err := server.StartServer(serverCtx, cfg.ServerAddr, cfg.DatasetSize, string(cfg.TestType))
-		if cleanup != nil {
-			logCleanup = cleanup
+	if cfg.EmbeddedServer() {
+		// Get log file path before starting server (only the Helix server logs requests)
+		if cfg.ServerImpl == config.ServerImplHelix {
+			logFilePath = server.GetLogFilePath(string(cfg.TestType))
 		}
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+
+		// Trust the generated certificate unless another CA was configured
+		var cert *tls.Certificate
+		if cfg.TLS {
+			generated, certPEM, err := server.GenerateCertificate(cfg.ServerAddr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating certificate: %v\n", err)
+				os.Exit(1)
+			}
+			cert = &generated
+			if cfg.TLSCA == "" {
+				cfg.TLSRoots = certPEM
+			}
+		}
+
+		mw := server.MiddlewareOf(cfg.Middleware)
+		if cfg.FaultFile != "" {
+			if mw.Faults, err = server.LoadFaults(cfg.FaultFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading faults: %v\n", err)
+				os.Exit(1)
+			}
 		}
-	}()
 
-	// Wait a moment for server to start
-	time.Sleep(500 * time.Millisecond)
+		ready := make(chan struct{})
+		serverErr := make(chan error, 1)
+		serverWg.Add(1)
+		go func() {
+			defer serverWg.Done()
+			_, cleanup, err := server.StartServer(serverCtx, server.Options{
+				Addr:        cfg.ServerAddr,
+				DatasetSize: cfg.DatasetSize,
+				TestType:    string(cfg.TestType),
+				Certificate: cert,
+				Impl:        cfg.ServerImpl,
+				Middleware:  mw,
+				Store:       cfg.Store,
+				StoreFile:   cfg.StoreFile,
+				AdminToken:  cfg.AdminToken,
+				Ready:       func() { close(ready) },
+			})
+			if cleanup != nil {
+				logCleanup = cleanup
+			}
+			if err != nil {
+				select {
+				case <-ready:
+					fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+				default:
+					serverErr <- err
+				}
+			}
+		}()
+
+		// Wait until the server is listening, failing fast if it can't start
+		select {
+		case <-ready:
+		case err := <-serverErr:
+			serverWg.Wait()
+			if logCleanup != nil {
+				logCleanup()
+			}
+			fmt.Fprintf(os.Stderr, "Error: embedded server failed to start: %v\n", err)
+			os.Exit(1)
+		case <-time.After(cfg.ReadyTimeout):
+			fmt.Fprintf(os.Stderr, "Error: embedded server not ready after %s\n", cfg.ReadyTimeout)
+			os.Exit(1)
+		}
+	}
 
 	// Create runner
-	r := runner.New(cfg, m)
+	r, err := runner.New(cfg, m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating runner: %v\n", err)
+		serverCancel()
+		serverWg.Wait()
+		os.Exit(1)
+	}
+
+	// Wait for the server to answer health checks
+	if err := r.WaitReady(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		serverCancel()
+		serverWg.Wait()
+		os.Exit(1)
+	}
 
-	// Start progress reporting
+	// Don't count the time spent waiting for the server
+	m.Reset()
+
+	// Start progress reporting (agents report their results only at the end)
 	progressDone := make(chan struct{})
 	var progressWg sync.WaitGroup
-	progressWg.Add(1)
-	go func() {
-		defer progressWg.Done()
-		report.PrintProgress(m, 1*time.Second, progressDone)
-	}()
+	if cfg.Mode != config.ModeCoordinator {
+		progressWg.Add(1)
+		go func() {
+			defer progressWg.Done()
+			report.PrintProgress(m, 1*time.Second, progressDone)
+		}()
+	}
 
 	// Run stress test
 	startTime := time.Now().Format("2006-01-02 15:04:05")
 	fmt.Printf("[%s] Starting stress test (type: %s, duration: %s, RPS: %d, concurrent: %d, dataset: %d items)...\n",
 		startTime, cfg.TestType, cfg.Duration, cfg.TargetRPS, cfg.Concurrent, cfg.DatasetSize)
+	if cfg.Warmup > 0 {
+		fmt.Printf("Warm-up: %s (excluded from results)\n", cfg.Warmup)
+	}
 	if logFilePath != "" {
 		fmt.Printf("Server logs: %s\n", logFilePath)
 	}
+	if !cfg.EmbeddedServer() {
+		fmt.Printf("Target: %s\n", cfg.BaseURL())
+	}
+	if cfg.Mode == config.ModeCoordinator {
+		fmt.Printf("Distributing load to %d agents: %s\n", len(cfg.Agents), strings.Join(cfg.Agents, ", "))
+	}
 	fmt.Println()
 
 	var testWg sync.WaitGroup
@@ -84,7 +223,22 @@ func main() {
 	go func() {
 		defer testWg.Done()
 		defer close(testDone)
-		if err := r.Run(ctx); err != nil {
+		var err error
+		if cfg.Mode == config.ModeCoordinator {
+			// The coordinator manages the dataset once for all agents, before they
+			// start and after every agent has finished
+			if err = r.RecordDataset(ctx, "start", cfg.Reseed != config.ReseedNone); err != nil {
+				err = fmt.Errorf("failed to prepare dataset: %w", err)
+			} else {
+				err = distributed.Coordinate(ctx, cfg, workload, m)
+				if ctx.Err() == nil {
+					_ = r.RecordDataset(ctx, "end", false)
+				}
+			}
+		} else {
+			err = r.Run(ctx)
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Test error: %v\n", err)
 		}
 	}()
@@ -129,3 +283,52 @@ func main() {
 		}
 	}
 }
+
+// runMatrix runs the load test --matrix-repeats times for every middleware
+// combination (matrix tests) or server implementation (compare tests), each against
+// a fresh embedded server, and writes the report.
+func runMatrix(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	variants := matrix.MiddlewareVariants(cfg)
+	generate := report.New(cfg, nil).GenerateMatrix
+	if cfg.TestType == config.TestTypeCompare {
+		variants = matrix.CompareVariants(cfg)
+		generate = report.New(cfg, nil).GenerateCompare
+	}
+
+	runs := len(variants) * cfg.MatrixRepeats
+	startTime := time.Now().Format("2006-01-02 15:04:05")
+	fmt.Printf("[%s] Starting %s test (%d runs of %s, RPS: %d, concurrent: %d, dataset: %d items)...\n",
+		startTime, cfg.TestType, runs, cfg.Warmup+cfg.Duration, cfg.TargetRPS, cfg.Concurrent, cfg.DatasetSize)
+
+	results, err := matrix.Run(ctx, cfg, variants, func(i int, v matrix.Variant, m *metrics.Metrics) func() {
+		fmt.Printf("\nRun %d/%d: %s\n", i+1, runs, v.Name)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.PrintProgress(m, 1*time.Second, done)
+		}()
+		return func() {
+			close(done)
+			wg.Wait()
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Test error: %v\n", err)
+	}
+	fmt.Println()
+	if len(results) == 0 {
+		os.Exit(1)
+	}
+
+	reportTime := time.Now().Format("2006-01-02 15:04:05")
+	fmt.Printf("[%s] Generating report...\n", reportTime)
+	if err := generate(results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating report: %v\n", err)
+		os.Exit(1)
+	}
+}
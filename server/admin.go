@@ -0,0 +1,63 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// AdminTokenHeader carries the token guarding the admin routes.
+const AdminTokenHeader = "X-Admin-Token"
+
+// Admin configures the admin routes of the test server, which reset, reseed,
+// snapshot and dump the item store:
+//
+//	POST /admin/reset            removes every item
+//	POST /admin/reseed?size=N    restores N pre-populated items (default DatasetSize)
+//	GET  /admin/snapshot         returns the item count and a checksum
+//	GET  /admin/dump             returns every item
+type Admin struct {
+	Token       string // Required in AdminTokenHeader; the routes aren't registered if empty
+	DatasetSize int    // Items restored by /admin/reseed by default
+}
+
+// ReseedRequest contains the query parameters of /admin/reseed.
+type ReseedRequest struct {
+	Size int `query:"size"`
+}
+
+// NewAdminToken returns a random admin token.
+func NewAdminToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate admin token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// adminAuth returns middleware rejecting requests without the admin token.
+func adminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reseed resets the store to size items, or to the dataset size if size isn't
+// positive, and returns its snapshot.
+func (a Admin) reseed(store ItemStore, size int) (StoreSnapshot, error) {
+	if size <= 0 {
+		size = a.DatasetSize
+	}
+	if err := store.Reset(size); err != nil {
+		return StoreSnapshot{}, err
+	}
+	return store.Snapshot()
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultConfig configures the faults injected into the responses of the test server.
+// The first rule matching a request applies.
+type FaultConfig struct {
+	Rules []FaultRule `json:"rules"`
+}
+
+// FaultRule configures the faults injected into the requests matching a route.
+// Probabilities are between 0 and 1 and are drawn independently for each request.
+type FaultRule struct {
+	// Route is "METHOD /path" or "/path", where the path may contain glob
+	// patterns (e.g., "GET /items/*"); "*" matches every request.
+	Route   string        `json:"route"`
+	Latency *LatencyFault `json:"latency,omitempty"`
+	Error   *ErrorFault   `json:"error,omitempty"`
+	Panic   float64       `json:"panic,omitempty"`   // Probability of panicking in the handler chain
+	Reset   float64       `json:"reset,omitempty"`   // Probability of resetting the connection instead of responding
+	Partial float64       `json:"partial,omitempty"` // Probability of closing the connection halfway through the response body
+
+	method  string
+	pattern string
+}
+
+// LatencyFault adds latency before the request is handled.
+type LatencyFault struct {
+	Probability  *float64 `json:"probability,omitempty"`  // Probability of adding latency (always if omitted)
+	Distribution string   `json:"distribution,omitempty"` // constant (default), uniform, or exponential
+	Min          Duration `json:"min,omitempty"`          // Constant latency, or lower bound for uniform
+	Max          Duration `json:"max,omitempty"`          // Upper bound for uniform
+	Mean         Duration `json:"mean,omitempty"`         // Mean for exponential
+}
+
+// ErrorFault responds with an error status instead of handling the request.
+type ErrorFault struct {
+	Probability float64 `json:"probability"`
+	Statuses    []int   `json:"statuses,omitempty"` // Picked at random (default 500)
+}
+
+// Duration is a time.Duration that is written as a string such as "25ms" in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"25ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadFaults reads and validates a fault injection configuration file.
+func LoadFaults(file string) (*FaultConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fault file: %w", err)
+	}
+
+	var cfg FaultConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse fault file: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fault file %s: %w", file, err)
+	}
+	return &cfg, nil
+}
+
+// validate checks the rules and prepares their route patterns.
+func (c *FaultConfig) validate() error {
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Route == "" {
+			return fmt.Errorf("rule %d: route is required", i+1)
+		}
+		r.method, r.pattern = "", r.Route
+		if method, pattern, ok := strings.Cut(r.Route, " "); ok {
+			r.method, r.pattern = strings.ToUpper(method), strings.TrimSpace(pattern)
+		}
+		if r.pattern != "*" {
+			if _, err := path.Match(r.pattern, "/"); err != nil {
+				return fmt.Errorf("rule %d: invalid route pattern %q", i+1, r.pattern)
+			}
+		}
+
+		for name, p := range map[string]float64{"panic": r.Panic, "reset": r.Reset, "partial": r.Partial} {
+			if p < 0 || p > 1 {
+				return fmt.Errorf("rule %d: %s probability must be between 0 and 1", i+1, name)
+			}
+		}
+		if l := r.Latency; l != nil {
+			if p := l.Probability; p != nil && (*p < 0 || *p > 1) {
+				return fmt.Errorf("rule %d: latency probability must be between 0 and 1", i+1)
+			}
+			switch l.Distribution {
+			case "", "constant", "exponential":
+			case "uniform":
+				if l.Max < l.Min {
+					return fmt.Errorf("rule %d: uniform latency max must not be below min", i+1)
+				}
+			default:
+				return fmt.Errorf("rule %d: invalid latency distribution: %s (must be constant, uniform, or exponential)", i+1, l.Distribution)
+			}
+		}
+		if e := r.Error; e != nil {
+			if e.Probability < 0 || e.Probability > 1 {
+				return fmt.Errorf("rule %d: error probability must be between 0 and 1", i+1)
+			}
+			for _, status := range e.Statuses {
+				if status < 400 || status > 599 {
+					return fmt.Errorf("rule %d: invalid error status %d", i+1, status)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// match returns the first rule matching the request, or nil.
+func (c *FaultConfig) match(r *http.Request) *FaultRule {
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.method != "" && rule.method != r.Method {
+			continue
+		}
+		if rule.pattern == "*" {
+			return rule
+		}
+		if ok, _ := path.Match(rule.pattern, r.URL.Path); ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+// delay returns the latency to add.
+func (l *LatencyFault) delay() time.Duration {
+	if l.Probability != nil && rand.Float64() >= *l.Probability {
+		return 0
+	}
+	switch l.Distribution {
+	case "uniform":
+		return time.Duration(l.Min) + time.Duration(rand.Int63n(int64(l.Max-l.Min)+1))
+	case "exponential":
+		return time.Duration(rand.ExpFloat64() * float64(l.Mean))
+	default:
+		return time.Duration(l.Min)
+	}
+}
+
+// Faults returns middleware injecting the configured faults. Installed after
+// Recover, injected panics are handled by it.
+func Faults(cfg *FaultConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Admin routes are never faulted, so the runner can always manage the dataset
+			rule := cfg.match(r)
+			if rule == nil || strings.HasPrefix(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if rule.Latency != nil {
+				if d := rule.Latency.delay(); d > 0 {
+					select {
+					case <-time.After(d):
+					case <-r.Context().Done():
+						return
+					}
+				}
+			}
+
+			if rule.Reset > 0 && rand.Float64() < rule.Reset {
+				resetConnection(w)
+				return
+			}
+
+			if rule.Panic > 0 && rand.Float64() < rule.Panic {
+				panic(fmt.Sprintf("injected fault: %s %s", r.Method, r.URL.Path))
+			}
+
+			if e := rule.Error; e != nil && rand.Float64() < e.Probability {
+				status := http.StatusInternalServerError
+				if len(e.Statuses) > 0 {
+					status = e.Statuses[rand.Intn(len(e.Statuses))]
+				}
+				writeError(w, status, "injected fault")
+				return
+			}
+
+			if rule.Partial > 0 && rand.Float64() < rule.Partial {
+				partialResponse(w, r, next)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// resetConnection closes the connection of the response without responding.
+func resetConnection(w http.ResponseWriter) {
+	closeConnection(w, true)
+}
+
+// closeConnection closes the connection of the response, flushing anything
+// written so far. With reset, a TCP connection is reset rather than closed
+// gracefully. Connections that can't be hijacked, such as HTTP/2 streams, are
+// aborted instead.
+func closeConnection(w http.ResponseWriter, reset bool) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if !reset {
+		conn.Close()
+		return
+	}
+	if tlsConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		conn = tlsConn.NetConn()
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// partialResponse handles the request, announces the full length of the response
+// body, and closes the connection after writing half of it.
+func partialResponse(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	next.ServeHTTP(buf, r)
+
+	for key, values := range buf.header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.body.Len()))
+	w.WriteHeader(buf.status)
+	_, _ = w.Write(buf.body.Bytes()[:buf.body.Len()/2])
+	closeConnection(w, false)
+}
+
+// bufferedResponse records a response in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header returns the response headers.
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+// WriteHeader records the status code.
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+// Write records the body.
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// fileStore appends every write to a log file and serves reads from it, keeping
+// only the offset of the latest record of each item in memory. The log is
+// truncated when the store is created, so every run starts empty. Records are
+// left to the page cache rather than synced.
+type fileStore struct {
+	mu      sync.RWMutex
+	file    *os.File
+	temp    bool          // Remove the file on Close
+	size    int64         // End of the log, where the next record is written
+	records map[int]entry // Latest record of each item
+	ids     sortedIDs
+	nextID  int
+}
+
+// entry locates a record in the log.
+type entry struct {
+	offset int64
+	length int
+}
+
+// logRecord is one line of the log. Deleted records only carry the ID.
+type logRecord struct {
+	Item
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// newFileStore creates a fileStore logging to file, or to a temporary file if it is empty.
+func newFileStore(file string) (*fileStore, error) {
+	var f *os.File
+	var err error
+	if file == "" {
+		f, err = os.CreateTemp("", "helix-stress-test-*.log")
+	} else {
+		f, err = os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store file: %w", err)
+	}
+	return &fileStore{
+		file:    f,
+		temp:    file == "",
+		records: make(map[int]entry),
+		nextID:  1,
+	}, nil
+}
+
+// appendRecords writes records at the end of the log and indexes them. Callers
+// hold the write lock.
+func (s *fileStore) appendRecords(records ...logRecord) error {
+	var buf []byte
+	entries := make([]entry, len(records))
+	for i, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		entries[i] = entry{offset: s.size + int64(len(buf)), length: len(line)}
+		buf = append(append(buf, line...), '\n')
+	}
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	s.size += int64(len(buf))
+
+	for i, r := range records {
+		if r.Deleted {
+			delete(s.records, r.ID)
+			s.ids.remove(r.ID)
+			continue
+		}
+		if _, ok := s.records[r.ID]; !ok {
+			s.ids.insert(r.ID)
+		}
+		s.records[r.ID] = entries[i]
+	}
+	return nil
+}
+
+// read reads the latest record of an item. Callers hold a lock.
+func (s *fileStore) read(id int) (Item, error) {
+	e, ok := s.records[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	buf := make([]byte, e.length)
+	if _, err := s.file.ReadAt(buf, e.offset); err != nil {
+		return Item{}, fmt.Errorf("failed to read store file: %w", err)
+	}
+	var r logRecord
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return Item{}, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return r.Item, nil
+}
+
+func (s *fileStore) PrePopulate(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.populate(size)
+}
+
+// populate appends the pre-populated items. Callers hold the write lock.
+func (s *fileStore) populate(size int) error {
+	records := make([]logRecord, size)
+	for i := range records {
+		records[i] = logRecord{Item: newItem(i + 1)}
+	}
+	if err := s.appendRecords(records...); err != nil {
+		return err
+	}
+	s.nextID = max(s.nextID, size+1)
+	return nil
+}
+
+// Reset truncates the log before appending the new items.
+func (s *fileStore) Reset(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate store file: %w", err)
+	}
+	s.size = 0
+	s.records = make(map[int]entry, size)
+	s.ids = nil
+	s.nextID = 1
+	return s.populate(size)
+}
+
+// Snapshot reads every item from the log.
+func (s *fileStore) Snapshot() (StoreSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var readErr error
+	snapshot := snapshotOf(s.ids, s.nextID, func(id int) (Item, bool) {
+		item, err := s.read(id)
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return item, err == nil
+	})
+	return snapshot, readErr
+}
+
+// Dump reads every item from the log.
+func (s *fileStore) Dump() (StoreDump, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var readErr error
+	dump := dumpOf(s.ids, s.nextID, func(id int) (Item, bool) {
+		item, err := s.read(id)
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return item, err == nil
+	})
+	return dump, readErr
+}
+
+func (s *fileStore) Create(name, value string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := Item{
+		ID:    s.nextID,
+		Name:  name,
+		Value: value,
+	}
+	if err := s.appendRecords(logRecord{Item: item}); err != nil {
+		return Item{}, err
+	}
+	s.nextID++
+	return item, nil
+}
+
+func (s *fileStore) Get(id int) (Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.read(id)
+}
+
+func (s *fileStore) Update(id int, name, value string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[id]; !ok {
+		return Item{}, ErrItemNotFound
+	}
+	item := Item{ID: id, Name: name, Value: value}
+	if err := s.appendRecords(logRecord{Item: item}); err != nil {
+		return Item{}, err
+	}
+	return item, nil
+}
+
+func (s *fileStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[id]; !ok {
+		return ErrItemNotFound
+	}
+	return s.appendRecords(logRecord{Item: Item{ID: id}, Deleted: true})
+}
+
+func (s *fileStore) List(req ListItemsRequest) (ListItemsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var readErr error
+	resp, err := listPage(s.ids, req, func(id int) (Item, bool) {
+		item, err := s.read(id)
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return item, err == nil
+	})
+	if err != nil {
+		return resp, err
+	}
+	return resp, readErr
+}
+
+func (s *fileStore) Close() error {
+	err := s.file.Close()
+	if s.temp {
+		if removeErr := os.Remove(s.file.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
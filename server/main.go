@@ -2,78 +2,23 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/kolosys/helix"
+	"github.com/kolosys/helix-stress-test/internal/netutil"
 	"github.com/kolosys/helix/middleware"
 )
 
-// Item represents a test item for CRUD operations.
-type Item struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Value string `json:"value"`
-}
-
-// ItemStore provides thread-safe in-memory storage.
-type ItemStore struct {
-	mu     sync.RWMutex
-	items  map[int]Item
-	nextID int
-}
-
-// NewItemStore creates a new ItemStore.
-func NewItemStore() *ItemStore {
-	return &ItemStore{
-		items:  make(map[int]Item),
-		nextID: 1,
-	}
-}
-
-// PrePopulate fills the store with a dataset of the specified size.
-func (s *ItemStore) PrePopulate(size int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for i := 1; i <= size; i++ {
-		s.items[i] = Item{
-			ID:    i,
-			Name:  fmt.Sprintf("Item-%d", i),
-			Value: fmt.Sprintf("value-%d", i),
-		}
-	}
-	s.nextID = size + 1
-}
-
-// GetRandomID returns a random ID from existing items (for testing).
-// Optimized to avoid allocations - uses a simple counter-based approach.
-func (s *ItemStore) GetRandomID() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if len(s.items) == 0 {
-		return 1
-	}
-
-	// Use a simple approach: return a random ID from 1 to len(items)
-	// This avoids allocating a slice and iterating over all items
-	// For stress testing, this is sufficient and much faster
-	count := len(s.items)
-	if count == 0 {
-		return 1
-	}
-
-	// Return ID in range [1, count] - works well with pre-populated datasets
-	// where IDs are sequential from 1 to datasetSize
-	return (count % 1000) + 1 // Cycle through first 1000 IDs
-}
-
 // Request/Response types for typed handlers
 type (
 	// GetItemRequest contains the item ID from the path.
@@ -83,16 +28,20 @@ type (
 
 	// ListItemsRequest contains query parameters for listing items.
 	ListItemsRequest struct {
-		Page  int `query:"page"`
-		Limit int `query:"limit"`
+		Page   int    `query:"page"`
+		Limit  int    `query:"limit"`
+		Cursor int    `query:"cursor"` // ID of the last item of the previous page, instead of page
+		Order  string `query:"order"`  // asc or desc
 	}
 
 	// ListItemsResponse is the response for listing items.
 	ListItemsResponse struct {
-		Items []Item `json:"items"`
-		Total int    `json:"total"`
-		Page  int    `json:"page"`
-		Limit int    `json:"limit"`
+		Items      []Item `json:"items"`
+		Total      int    `json:"total"`
+		Page       int    `json:"page,omitempty"`
+		Limit      int    `json:"limit"`
+		Order      string `json:"order"`
+		NextCursor int    `json:"next_cursor,omitempty"`
 	}
 
 	// CreateItemRequest contains the data for creating an item.
@@ -135,18 +84,87 @@ func GetLogFilePath(testType string) string {
 	return filepath.Join(logsDir, fmt.Sprintf("server-%s-%s.log", testType, timestamp))
 }
 
-// NewServer creates and configures a test server with all helix features.
-// datasetSize specifies how many items to pre-populate (0 for empty store).
-// testType is the type of test being run (e.g., "load", "spike", "endurance").
-// Returns the server, log file path, and a cleanup function to close the log file.
-func NewServer(addr string, datasetSize int, testType string) (*helix.Server, string, func() error) {
-	store := NewItemStore()
+// Middleware names accepted by MiddlewareOf, in the order the middleware is installed.
+const (
+	MiddlewareRequestID = "requestid"
+	MiddlewareLogger    = "logger"
+	MiddlewareRecover   = "recover"
+	MiddlewareCompress  = "compress"
+)
+
+// Middleware selects the middleware installed on the test server.
+type Middleware struct {
+	RequestID bool
+	Logger    bool // Only installed if the log file can be created
+	Recover   bool
+	Compress  bool
+	Faults    *FaultConfig // Fault injection, installed after Recover (nil for none)
+}
+
+// AllMiddleware installs every middleware.
+var AllMiddleware = Middleware{RequestID: true, Logger: true, Recover: true, Compress: true}
+
+// MiddlewareOf returns the middleware selection enabling the named middleware.
+// Unknown names are ignored.
+func MiddlewareOf(names []string) Middleware {
+	var mw Middleware
+	for _, name := range names {
+		mw = mw.With(name, true)
+	}
+	return mw
+}
 
-	// Pre-populate dataset if specified
-	if datasetSize > 0 {
-		store.PrePopulate(datasetSize)
+// With returns a copy of the selection with the named middleware enabled or disabled.
+func (mw Middleware) With(name string, enabled bool) Middleware {
+	switch name {
+	case MiddlewareRequestID:
+		mw.RequestID = enabled
+	case MiddlewareLogger:
+		mw.Logger = enabled
+	case MiddlewareRecover:
+		mw.Recover = enabled
+	case MiddlewareCompress:
+		mw.Compress = enabled
 	}
+	return mw
+}
 
+// Enabled reports whether the named middleware is enabled.
+func (mw Middleware) Enabled(name string) bool {
+	switch name {
+	case MiddlewareRequestID:
+		return mw.RequestID
+	case MiddlewareLogger:
+		return mw.Logger
+	case MiddlewareRecover:
+		return mw.Recover
+	case MiddlewareCompress:
+		return mw.Compress
+	}
+	return false
+}
+
+// String returns the enabled middleware joined with "+", or "none".
+func (mw Middleware) String() string {
+	var names []string
+	for _, name := range []string{MiddlewareRequestID, MiddlewareLogger, MiddlewareRecover, MiddlewareCompress} {
+		if mw.Enabled(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
+
+// NewServer creates and configures a test server with all helix features.
+// store holds the items served by the /items routes, and admin configures the
+// routes managing it.
+// testType is the type of test being run (e.g., "load", "spike", "endurance").
+// mw selects the middleware to install, and options configure how the server listens.
+// Returns the server, log file path, and a cleanup function to close the log file.
+func NewServer(store ItemStore, admin Admin, testType string, mw Middleware, options ...helix.Option) (*helix.Server, string, func() error) {
 	// Create logs directory if it doesn't exist
 	logsDir := "logs"
 	if err := os.MkdirAll(logsDir, 0755); err != nil {
@@ -176,14 +194,15 @@ func NewServer(addr string, datasetSize int, testType string) (*helix.Server, st
 	}
 
 	// Create server with logger middleware writing to file
-	s := helix.New(
-		helix.WithAddr(addr),
+	s := helix.New(append([]helix.Option{
 		helix.HideBanner(), // Hide banner for cleaner output
-	)
-	s.Use(middleware.RequestID())
+	}, options...)...)
+	if mw.RequestID {
+		s.Use(middleware.RequestID())
+	}
 
 	// Add logger middleware with file output
-	if logWriter != nil {
+	if mw.Logger && logWriter != nil {
 		loggerConfig := middleware.LoggerConfig{
 			Format:        middleware.LogFormatDev,
 			Output:        logWriter,
@@ -192,10 +211,19 @@ func NewServer(addr string, datasetSize int, testType string) (*helix.Server, st
 		s.Use(middleware.LoggerWithConfig(loggerConfig))
 	}
 
-	s.Use(middleware.Recover())
+	if mw.Recover {
+		s.Use(middleware.Recover())
+	}
+
+	// Inject faults inside Recover so injected panics exercise it
+	if mw.Faults != nil {
+		s.Use(Faults(mw.Faults))
+	}
 
 	// Add additional middleware to test middleware chains
-	s.Use(middleware.Compress())
+	if mw.Compress {
+		s.Use(middleware.Compress())
+	}
 
 	// Basic routes - simple GET/POST/PUT/DELETE
 	s.GET("/", helix.HandleCtx(func(c *helix.Ctx) error {
@@ -268,118 +296,31 @@ func NewServer(addr string, datasetSize int, testType string) (*helix.Server, st
 
 	// JSON body binding - POST
 	s.POST("/items", helix.HandleCreated(func(ctx context.Context, req CreateItemRequest) (Item, error) {
-		store.mu.Lock()
-		defer store.mu.Unlock()
-
-		item := Item{
-			ID:    store.nextID,
-			Name:  req.Name,
-			Value: req.Value,
-		}
-		store.items[item.ID] = item
-		store.nextID++
-
-		return item, nil
+		item, err := store.Create(req.Name, req.Value)
+		return item, storeError(err, 0)
 	}))
 
 	// JSON body binding - PUT
 	s.PUT("/items/{id}", helix.Handle(func(ctx context.Context, req UpdateItemRequest) (Item, error) {
-		store.mu.Lock()
-		defer store.mu.Unlock()
-
-		item, ok := store.items[req.ID]
-		if !ok {
-			return Item{}, helix.NotFoundf("item %d not found", req.ID)
-		}
-
-		// Update the item
-		item.Name = req.Name
-		item.Value = req.Value
-		store.items[req.ID] = item
-
-		return item, nil
+		item, err := store.Update(req.ID, req.Name, req.Value)
+		return item, storeError(err, req.ID)
 	}))
 
 	// Typed handler - GET with path binding
 	s.GET("/items/{id}", helix.Handle(func(ctx context.Context, req GetItemRequest) (Item, error) {
-		store.mu.RLock()
-		defer store.mu.RUnlock()
-
-		item, ok := store.items[req.ID]
-		if !ok {
-			return Item{}, helix.NotFoundf("item %d not found", req.ID)
-		}
-
-		return item, nil
+		item, err := store.Get(req.ID)
+		return item, storeError(err, req.ID)
 	}))
 
 	// Typed handler - GET with query binding
 	s.GET("/items", helix.Handle(func(ctx context.Context, req ListItemsRequest) (ListItemsResponse, error) {
-		store.mu.RLock()
-		total := len(store.items)
-
-		if req.Page <= 0 {
-			req.Page = 1
-		}
-		if req.Limit <= 0 {
-			req.Limit = 10
-		}
-
-		// Optimized pagination: only iterate through items we need
-		// This avoids copying all 10,000 items before pagination
-		start := (req.Page - 1) * req.Limit
-		end := start + req.Limit
-
-		if start >= total {
-			store.mu.RUnlock()
-			return ListItemsResponse{
-				Items: []Item{},
-				Total: total,
-				Page:  req.Page,
-				Limit: req.Limit,
-			}, nil
-		}
-
-		if end > total {
-			end = total
-		}
-
-		// Iterate through items map, collecting only what we need
-		// This is much faster than copying all items first
-		items := make([]Item, 0, req.Limit)
-		skipped := 0
-		for _, item := range store.items {
-			if skipped < start {
-				skipped++
-				continue
-			}
-			if len(items) >= req.Limit {
-				break
-			}
-			items = append(items, item)
-		}
-
-		store.mu.RUnlock()
-
-		return ListItemsResponse{
-			Items: items,
-			Total: total,
-			Page:  req.Page,
-			Limit: req.Limit,
-		}, nil
+		resp, err := store.List(req)
+		return resp, storeError(err, 0)
 	}))
 
 	// Typed handler - DELETE
 	s.DELETE("/items/{id}", helix.HandleNoResponse(func(ctx context.Context, req DeleteItemRequest) error {
-		store.mu.Lock()
-		defer store.mu.Unlock()
-
-		if _, ok := store.items[req.ID]; !ok {
-			return helix.NotFoundf("item %d not found", req.ID)
-		}
-
-		delete(store.items, req.ID)
-		return nil
+		return storeError(store.Delete(req.ID), req.ID)
 	}))
 
 	// Error handling - various error types
@@ -463,15 +404,220 @@ func NewServer(addr string, datasetSize int, testType string) (*helix.Server, st
 		})
 	}))
 
+	// Large and streamed responses
+	s.GET("/large", helix.Handle(func(ctx context.Context, req LargeRequest) ([]Item, error) {
+		return largeItems(req.Items), nil
+	}))
+
+	s.GET("/stream", helix.HandleCtx(func(c *helix.Ctx) error {
+		req, err := parseStreamRequest(c.Query)
+		if err != nil {
+			return helix.BadRequestf("%v", err)
+		}
+		c.SetHeader("Content-Type", ndjsonContentType)
+		c.Response.WriteHeader(http.StatusOK)
+		writeStream(c.Context(), c.Response, c.Flush, req)
+		return nil
+	}))
+
+	s.GET("/sse", helix.HandleCtx(func(c *helix.Ctx) error {
+		req, err := parseSSERequest(c.Query)
+		if err != nil {
+			return helix.BadRequestf("%v", err)
+		}
+		c.SetHeader("Content-Type", sseContentType)
+		c.SetHeader("Cache-Control", "no-cache")
+		c.Response.WriteHeader(http.StatusOK)
+		writeSSE(c.Context(), c.Response, c.Flush, req)
+		return nil
+	}))
+
+	// Uploads - multipart and urlencoded form binding, and raw bodies
+	uploads := s.Group("/upload", limitBody(MaxUploadSize))
+
+	uploads.POST("/multipart", helix.Handle(func(ctx context.Context, req MultipartUploadRequest) (UploadResponse, error) {
+		return req.response(), nil
+	}))
+
+	uploads.POST("/form", helix.Handle(func(ctx context.Context, req FormUploadRequest) (UploadResponse, error) {
+		return req.response(), nil
+	}))
+
+	uploads.POST("/binary", helix.HandleCtx(func(c *helix.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Request.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			// The body had no declared length; answer like limitBody does
+			writeError(c.Response, http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit))
+			return nil
+		}
+		if err != nil {
+			return helix.BadRequestf("invalid upload: %v", err)
+		}
+		return c.OK(UploadResponse{Bytes: n})
+	}))
+
+	// Admin routes - guarded by the admin token
+	if admin.Token != "" {
+		adminGroup := s.Group("/admin", adminAuth(admin.Token))
+
+		adminGroup.POST("/reset", helix.Handle(func(ctx context.Context, req struct{}) (StoreSnapshot, error) {
+			if err := store.Reset(0); err != nil {
+				return StoreSnapshot{}, storeError(err, 0)
+			}
+			snapshot, err := store.Snapshot()
+			return snapshot, storeError(err, 0)
+		}))
+
+		adminGroup.POST("/reseed", helix.Handle(func(ctx context.Context, req ReseedRequest) (StoreSnapshot, error) {
+			snapshot, err := admin.reseed(store, req.Size)
+			return snapshot, storeError(err, 0)
+		}))
+
+		adminGroup.GET("/snapshot", helix.Handle(func(ctx context.Context, req struct{}) (StoreSnapshot, error) {
+			snapshot, err := store.Snapshot()
+			return snapshot, storeError(err, 0)
+		}))
+
+		adminGroup.GET("/dump", helix.Handle(func(ctx context.Context, req struct{}) (StoreDump, error) {
+			dump, err := store.Dump()
+			return dump, storeError(err, 0)
+		}))
+	}
+
 	return s, logFile, cleanup
 }
 
+// storeError converts a store error into the matching Helix error.
+func storeError(err error, id int) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrItemNotFound):
+		return helix.NotFoundf("item %d not found", id)
+	case errors.Is(err, ErrInvalidOrder):
+		return helix.BadRequestf("%v", err)
+	default:
+		return helix.Internalf("%v", err)
+	}
+}
+
+// shutdownTimeout bounds how long in-flight requests may take to finish on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Server implementations accepted in Options.Impl.
+const (
+	ImplHelix   = "helix"   // The Helix server created by NewServer
+	ImplNetHTTP = "nethttp" // The plain net/http server created by NewNetHTTPServer
+)
+
+// Options configures the embedded test server.
+type Options struct {
+	Addr        string           // host:port, or unix:///path.sock for a Unix domain socket
+	DatasetSize int              // Number of items to pre-populate (0 for empty store)
+	TestType    string           // Type of test being run (e.g., "load", "spike", "endurance")
+	Certificate *tls.Certificate // Serve over TLS with this certificate (nil for plain HTTP)
+	Impl        string           // Server implementation (empty for helix)
+	Middleware  Middleware
+	Store       string // Item store backend (empty for mutex)
+	StoreFile   string // Log of the file store (empty for a temporary file)
+	AdminToken  string // Token guarding the admin routes (empty to disable them)
+	Ready       func() // Called once the listener is bound and the dataset is loaded
+}
+
 // StartServer starts the test server and blocks until shutdown.
-// datasetSize specifies how many items to pre-populate (0 for empty store).
-// testType is the type of test being run (e.g., "load", "spike", "endurance").
+// The server accepts HTTP/1.1 and HTTP/2, over TLS if enabled or as h2c otherwise.
+// The Helix implementation is used unless another one is selected; it serves on the
+// bound listener and runs the Ready callback from its start hook.
+// The Ready callback is invoked once the server accepts connections; if the
+// listener cannot be bound StartServer returns the error without calling it.
 // Returns the log file path and cleanup function.
-func StartServer(ctx context.Context, addr string, datasetSize int, testType string) (string, func() error, error) {
-	s, logFile, cleanup := NewServer(addr, datasetSize, testType)
-	err := s.Run(ctx)
-	return logFile, cleanup, err
+func StartServer(ctx context.Context, opts Options) (string, func() error, error) {
+	if opts.Impl != ImplHelix && opts.Impl != "" && opts.Impl != ImplNetHTTP {
+		return "", nil, fmt.Errorf("unknown server implementation: %s", opts.Impl)
+	}
+
+	store, err := NewItemStore(opts.Store, opts.StoreFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if opts.DatasetSize > 0 {
+		if err := store.PrePopulate(opts.DatasetSize); err != nil {
+			store.Close()
+			return "", nil, fmt.Errorf("failed to pre-populate store: %w", err)
+		}
+	}
+	closeLog := func() error { return nil }
+	cleanup := func() error {
+		return errors.Join(closeLog(), store.Close())
+	}
+
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	var tlsConfig *tls.Config
+	if opts.Certificate != nil {
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{*opts.Certificate}}
+	}
+
+	ln, err := netutil.Listen(opts.Addr)
+	if err != nil {
+		return "", cleanup, fmt.Errorf("failed to listen on %s: %w", opts.Addr, err)
+	}
+
+	admin := Admin{Token: opts.AdminToken, DatasetSize: opts.DatasetSize}
+	if opts.Impl == ImplNetHTTP {
+		return "", cleanup, serveNetHTTP(ctx, ln, NewNetHTTPServer(store, admin), &protocols, tlsConfig, opts.Ready)
+	}
+
+	options := []helix.Option{
+		helix.WithListener(ln),
+		helix.WithProtocols(&protocols),
+		helix.WithGracePeriod(shutdownTimeout),
+	}
+	if tlsConfig != nil {
+		options = append(options, helix.WithTLSConfig(tlsConfig))
+	}
+	s, logFile, closeLogFile := NewServer(store, admin, opts.TestType, opts.Middleware, options...)
+	closeLog = closeLogFile
+	s.OnStart(func(*helix.Server) {
+		if opts.Ready != nil {
+			opts.Ready()
+		}
+	})
+	if err := s.Run(ctx); err != nil {
+		return logFile, cleanup, fmt.Errorf("server failed: %w", err)
+	}
+	return logFile, cleanup, nil
+}
+
+// serveNetHTTP serves handler on ln with a plain net/http server until the
+// context is canceled, then shuts it down gracefully.
+func serveNetHTTP(ctx context.Context, ln net.Listener, handler http.Handler, protocols *http.Protocols, tlsConfig *tls.Config, ready func()) error {
+	srv := &http.Server{Handler: handler, Protocols: protocols, TLSConfig: tlsConfig}
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if ready != nil {
+		ready()
+	}
+
+	serve := srv.Serve
+	if tlsConfig != nil {
+		serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+	}
+	if err := serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	<-shutdownDone
+	return nil
 }
@@ -0,0 +1,441 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// NewNetHTTPServer creates a plain net/http version of the test server with the
+// same routes as NewServer, for measuring the overhead of the framework. Routing
+// uses http.ServeMux, and requests and responses are decoded and encoded by hand.
+// It installs no middleware.
+// store holds the items served by the /items routes, and admin configures the
+// routes managing it.
+func NewNetHTTPServer(store ItemStore, admin Admin) http.Handler {
+	mux := http.NewServeMux()
+
+	// Basic routes
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"message": "Helix Stress Test Server",
+			"status":  "ready",
+		})
+	})
+
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"message": "pong",
+		})
+	})
+
+	// Path parameters
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		writeJSON(w, http.StatusOK, map[string]string{
+			"id":   id,
+			"name": fmt.Sprintf("User-%s", id),
+		})
+	})
+
+	mux.HandleFunc("GET /users/{id}/posts/{postID}", func(w http.ResponseWriter, r *http.Request) {
+		userID := r.PathValue("id")
+		postID := r.PathValue("postID")
+		writeJSON(w, http.StatusOK, map[string]string{
+			"userID": userID,
+			"postID": postID,
+			"title":  fmt.Sprintf("Post %s by User %s", postID, userID),
+		})
+	})
+
+	// Query parameters
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			limit = 10
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"query": query,
+			"limit": limit,
+			"results": []string{
+				fmt.Sprintf("Result 1 for '%s'", query),
+				fmt.Sprintf("Result 2 for '%s'", query),
+			},
+		})
+	})
+
+	mux.HandleFunc("GET /api/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		sort, order := q.Get("sort"), q.Get("order")
+		writeJSON(w, http.StatusOK, map[string]any{
+			"search": q.Get("search"),
+			"sort":   sort,
+			"order":  order,
+			"results": []string{
+				fmt.Sprintf("Result sorted by %s in %s order", sort, order),
+			},
+		})
+	})
+
+	mux.HandleFunc("GET /categories/{category}/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		category := r.PathValue("category")
+		writeJSON(w, http.StatusOK, map[string]any{
+			"category": category,
+			"id":       id,
+			"name":     fmt.Sprintf("Item %d in %s", id, category),
+		})
+	})
+
+	// Items CRUD
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		var req CreateItemRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		item, err := store.Create(req.Name, req.Value)
+		if err != nil {
+			writeStoreError(w, err, 0)
+			return
+		}
+		writeJSON(w, http.StatusCreated, item)
+	})
+
+	mux.HandleFunc("PUT /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		var req UpdateItemRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		item, err := store.Update(id, req.Name, req.Value)
+		if err != nil {
+			writeStoreError(w, err, id)
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	})
+
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		item, err := store.Get(id)
+		if err != nil {
+			writeStoreError(w, err, id)
+			return
+		}
+		writeJSON(w, http.StatusOK, item)
+	})
+
+	mux.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := ListItemsRequest{Order: q.Get("order")}
+		req.Page, _ = strconv.Atoi(q.Get("page"))
+		req.Limit, _ = strconv.Atoi(q.Get("limit"))
+		req.Cursor, _ = strconv.Atoi(q.Get("cursor"))
+		resp, err := store.List(req)
+		if err != nil {
+			writeStoreError(w, err, 0)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("DELETE /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r)
+		if !ok {
+			return
+		}
+		if err := store.Delete(id); err != nil {
+			writeStoreError(w, err, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Error handling
+	mux.HandleFunc("GET /error/400", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusBadRequest, "bad request error")
+	})
+
+	mux.HandleFunc("GET /error/404", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "resource not found")
+	})
+
+	mux.HandleFunc("GET /error/500", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusInternalServerError, "internal server error")
+	})
+
+	// Middleware chain test
+	mux.HandleFunc("GET /middleware/test", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Middleware-Test", "true")
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":      "middleware chain test",
+			"middleware":   true,
+			"requestID":    r.Header.Get("X-Request-ID"),
+			"customHeader": w.Header().Get("X-Middleware-Test"),
+		})
+	})
+
+	// Resource routes
+	mux.HandleFunc("GET /products", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"products": []map[string]any{
+				{"id": 1, "name": "Product 1"},
+				{"id": 2, "name": "Product 2"},
+			},
+		})
+	})
+
+	mux.HandleFunc("POST /products", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if !readJSON(w, r, &req) {
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"id":   3,
+			"name": req["name"],
+		})
+	})
+
+	mux.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		idInt, _ := strconv.Atoi(id)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":   idInt,
+			"name": fmt.Sprintf("Product %s", id),
+		})
+	})
+
+	mux.HandleFunc("PUT /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if !readJSON(w, r, &req) {
+			return
+		}
+		idInt, _ := strconv.Atoi(r.PathValue("id"))
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":   idInt,
+			"name": req["name"],
+		})
+	})
+
+	mux.HandleFunc("DELETE /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Health check endpoint
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": "healthy",
+		})
+	})
+
+	// Large and streamed responses
+	mux.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+		n, err := queryInt(r.URL.Query().Get, "items", DefaultLargeItems, MaxLargeItems)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, largeItems(n))
+	})
+
+	mux.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseStreamRequest(r.URL.Query().Get)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+		writeStream(r.Context(), w, flusher(w), req)
+	})
+
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		req, err := parseSSERequest(r.URL.Query().Get)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", sseContentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		writeSSE(r.Context(), w, flusher(w), req)
+	})
+
+	// Uploads - multipart, urlencoded and raw bodies
+	mux.HandleFunc("POST /upload/multipart", func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+		if err := r.ParseMultipartForm(MultipartMaxMemory); err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		var resp UploadResponse
+		for _, values := range r.MultipartForm.Value {
+			for _, v := range values {
+				resp.Fields++
+				resp.Bytes += int64(len(v))
+			}
+		}
+		for _, files := range r.MultipartForm.File {
+			for _, f := range files {
+				resp.Files++
+				resp.Bytes += f.Size
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("POST /upload/form", func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
+		if err := r.ParseForm(); err != nil {
+			writeUploadError(w, err)
+			return
+		}
+
+		var resp UploadResponse
+		for _, values := range r.PostForm {
+			for _, v := range values {
+				resp.Fields++
+				resp.Bytes += int64(len(v))
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("POST /upload/binary", func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, http.MaxBytesReader(w, r.Body, MaxUploadSize))
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, UploadResponse{Bytes: n})
+	})
+
+	// Admin routes
+	if admin.Token != "" {
+		adminMux := http.NewServeMux()
+		adminMux.HandleFunc("POST /admin/reset", func(w http.ResponseWriter, r *http.Request) {
+			if err := store.Reset(0); err != nil {
+				writeStoreError(w, err, 0)
+				return
+			}
+			writeSnapshot(w, store)
+		})
+
+		adminMux.HandleFunc("POST /admin/reseed", func(w http.ResponseWriter, r *http.Request) {
+			size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+			snapshot, err := admin.reseed(store, size)
+			if err != nil {
+				writeStoreError(w, err, 0)
+				return
+			}
+			writeJSON(w, http.StatusOK, snapshot)
+		})
+
+		adminMux.HandleFunc("GET /admin/snapshot", func(w http.ResponseWriter, r *http.Request) {
+			writeSnapshot(w, store)
+		})
+
+		adminMux.HandleFunc("GET /admin/dump", func(w http.ResponseWriter, r *http.Request) {
+			dump, err := store.Dump()
+			if err != nil {
+				writeStoreError(w, err, 0)
+				return
+			}
+			writeJSON(w, http.StatusOK, dump)
+		})
+
+		mux.Handle("/admin/", adminAuth(admin.Token)(adminMux))
+	}
+
+	return mux
+}
+
+// writeSnapshot writes the snapshot of the store.
+func writeSnapshot(w http.ResponseWriter, store ItemStore) {
+	snapshot, err := store.Snapshot()
+	if err != nil {
+		writeStoreError(w, err, 0)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// flusher returns a function flushing w. Servers that can't flush still deliver
+// the response, only later.
+func flusher(w http.ResponseWriter) func() {
+	rc := http.NewResponseController(w)
+	return func() {
+		_ = rc.Flush()
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"status": status,
+		"error":  message,
+	})
+}
+
+// writeUploadError writes the response matching an upload parsing error.
+func writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid upload: %v", err))
+}
+
+// writeStoreError writes the response matching a store error.
+func writeStoreError(w http.ResponseWriter, err error, id int) {
+	switch {
+	case errors.Is(err, ErrItemNotFound):
+		writeError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+	case errors.Is(err, ErrInvalidOrder):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// readJSON decodes the request body into v, writing a 400 response if it is invalid.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+// pathID parses the id path parameter, writing a 400 response if it is not a number.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// shardCount is the number of shards of the sharded store.
+const shardCount = 32
+
+// shardedStore spreads items over shards by ID, each with its own lock and ordered
+// index, so writes to different items rarely contend. Listing locks every shard
+// and merges their indexes.
+type shardedStore struct {
+	shards [shardCount]shard
+	nextID atomic.Int64
+	epoch  atomic.Int64 // Incremented by every reset, under the lock of every shard
+}
+
+// shard holds the items whose ID maps to it.
+type shard struct {
+	mu    sync.RWMutex
+	items map[int]Item
+	ids   sortedIDs
+}
+
+// newShardedStore creates an empty shardedStore.
+func newShardedStore() *shardedStore {
+	s := &shardedStore{}
+	for i := range s.shards {
+		s.shards[i].items = make(map[int]Item)
+	}
+	return s
+}
+
+// shard returns the shard holding id.
+func (s *shardedStore) shard(id int) *shard {
+	return &s.shards[uint(id)%shardCount]
+}
+
+func (s *shardedStore) PrePopulate(size int) error {
+	s.lockAll()
+	defer s.unlockAll()
+
+	s.populate(size)
+	return nil
+}
+
+// populate adds the pre-populated items. Callers hold the write lock of every shard.
+func (s *shardedStore) populate(size int) {
+	for i := 1; i <= size; i++ {
+		sh := s.shard(i)
+		sh.items[i] = newItem(i)
+		sh.ids.insert(i)
+	}
+	if s.nextID.Load() < int64(size) {
+		s.nextID.Store(int64(size))
+	}
+}
+
+func (s *shardedStore) Reset(size int) error {
+	s.lockAll()
+	defer s.unlockAll()
+
+	for i := range s.shards {
+		s.shards[i].items = make(map[int]Item, size/shardCount+1)
+		s.shards[i].ids = nil
+	}
+	s.nextID.Store(0)
+	s.epoch.Add(1)
+	s.populate(size)
+	return nil
+}
+
+func (s *shardedStore) Snapshot() (StoreSnapshot, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	return snapshotOf(shardIndex{s}, int(s.nextID.Load())+1, func(id int) (Item, bool) {
+		item, ok := s.shard(id).items[id]
+		return item, ok
+	}), nil
+}
+
+func (s *shardedStore) Dump() (StoreDump, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	return dumpOf(shardIndex{s}, int(s.nextID.Load())+1, func(id int) (Item, bool) {
+		item, ok := s.shard(id).items[id]
+		return item, ok
+	}), nil
+}
+
+// lockAll takes the write lock of every shard, in order.
+func (s *shardedStore) lockAll() {
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+	}
+}
+
+// unlockAll releases the write lock of every shard.
+func (s *shardedStore) unlockAll() {
+	for i := range s.shards {
+		s.shards[i].mu.Unlock()
+	}
+}
+
+// rlockAll takes the read lock of every shard, in order.
+func (s *shardedStore) rlockAll() {
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+	}
+}
+
+// runlockAll releases the read lock of every shard.
+func (s *shardedStore) runlockAll() {
+	for i := range s.shards {
+		s.shards[i].mu.RUnlock()
+	}
+}
+
+// Create takes the ID before it knows which shard to lock, so a reset in between
+// would leave it with an ID of the old counter. It retries with a new ID if the
+// epoch changed by the time it holds the shard lock.
+func (s *shardedStore) Create(name, value string) (Item, error) {
+	for {
+		epoch := s.epoch.Load()
+		item := Item{
+			ID:    int(s.nextID.Add(1)),
+			Name:  name,
+			Value: value,
+		}
+		sh := s.shard(item.ID)
+		sh.mu.Lock()
+		if s.epoch.Load() != epoch {
+			sh.mu.Unlock()
+			continue
+		}
+		sh.items[item.ID] = item
+		sh.ids.insert(item.ID)
+		sh.mu.Unlock()
+		return item, nil
+	}
+}
+
+func (s *shardedStore) Get(id int) (Item, error) {
+	sh := s.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	item, ok := sh.items[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	return item, nil
+}
+
+func (s *shardedStore) Update(id int, name, value string) (Item, error) {
+	sh := s.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	item, ok := sh.items[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	item.Name = name
+	item.Value = value
+	sh.items[id] = item
+	return item, nil
+}
+
+func (s *shardedStore) Delete(id int) error {
+	sh := s.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, ok := sh.items[id]; !ok {
+		return ErrItemNotFound
+	}
+	delete(sh.items, id)
+	sh.ids.remove(id)
+	return nil
+}
+
+func (s *shardedStore) List(req ListItemsRequest) (ListItemsResponse, error) {
+	s.rlockAll()
+	defer s.runlockAll()
+
+	return listPage(shardIndex{s}, req, func(id int) (Item, bool) {
+		item, ok := s.shard(id).items[id]
+		return item, ok
+	})
+}
+
+func (s *shardedStore) Close() error {
+	return nil
+}
+
+// shardIndex merges the indexes of the shards. Callers hold the read lock of every shard.
+type shardIndex struct {
+	s *shardedStore
+}
+
+func (x shardIndex) size() int {
+	var n int
+	for i := range x.s.shards {
+		n += len(x.s.shards[i].ids)
+	}
+	return n
+}
+
+func (x shardIndex) countBelow(id int) int {
+	var n int
+	for i := range x.s.shards {
+		n += x.s.shards[i].ids.countBelow(id)
+	}
+	return n
+}
+
+// scan finds the ID at pos by binary search over the ID range, then merges the
+// shards from there, so the cost doesn't grow with pos.
+func (x shardIndex) scan(pos, n int, desc bool) []int {
+	// The ID at pos is the smallest id with more than pos IDs up to it
+	lo, hi := 1, int(x.s.nextID.Load())
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if x.countBelow(mid+1) > pos {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	// Position every shard at the first ID of the scan in its direction
+	var next [shardCount]int
+	for i := range x.s.shards {
+		ids := x.s.shards[i].ids
+		if desc {
+			above, _ := slices.BinarySearch(ids, lo+1)
+			next[i] = above - 1
+		} else {
+			next[i], _ = slices.BinarySearch(ids, lo)
+		}
+	}
+
+	ids := make([]int, 0, n)
+	for len(ids) < n {
+		best := -1
+		for i := range x.s.shards {
+			shardIDs := x.s.shards[i].ids
+			if next[i] < 0 || next[i] >= len(shardIDs) {
+				continue
+			}
+			if best < 0 || (shardIDs[next[i]] < x.s.shards[best].ids[next[best]]) != desc {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		ids = append(ids, x.s.shards[best].ids[next[best]])
+		if desc {
+			next[best]--
+		} else {
+			next[best]++
+		}
+	}
+	return ids
+}
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+// Item represents a test item for CRUD operations.
+type Item struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Sort orders accepted by List.
+const (
+	OrderAsc  = "asc"  // Ascending IDs (default)
+	OrderDesc = "desc" // Descending IDs
+)
+
+// Store errors.
+var (
+	ErrItemNotFound = errors.New("item not found")
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+// Store backends accepted by NewItemStore.
+const (
+	StoreMutex   = "mutex"   // One map behind a sync.RWMutex
+	StoreSharded = "sharded" // Maps sharded by ID, each behind its own sync.RWMutex
+	StoreSyncMap = "syncmap" // A sync.Map, with a mutex only around the ordered index
+	StoreFile    = "file"    // An append-only log file with an in-memory index
+)
+
+// ItemStore stores the items of the test server. Implementations are safe for
+// concurrent use and index items by ID in ascending order, so pages are stable and
+// cost the same whatever their position.
+type ItemStore interface {
+	// PrePopulate fills the store with a dataset of the specified size.
+	PrePopulate(size int) error
+	// Reset removes every item and pre-populates size items, restarting IDs after them.
+	Reset(size int) error
+	// Snapshot returns the item count and a checksum of the contents.
+	Snapshot() (StoreSnapshot, error)
+	// Dump returns every item in ID order.
+	Dump() (StoreDump, error)
+	// Create adds an item with the next ID and returns it.
+	Create(name, value string) (Item, error)
+	// Get returns the item with the given ID, or ErrItemNotFound.
+	Get(id int) (Item, error)
+	// Update replaces the name and value of an existing item and returns it, or ErrItemNotFound.
+	Update(id int, name, value string) (Item, error)
+	// Delete removes the item with the given ID, or returns ErrItemNotFound.
+	Delete(id int) error
+	// List returns a page of items sorted by ID. Pages start at 1 and hold 10
+	// items by default. With a cursor, the page starts after the item with that
+	// ID instead of at a page number, so it stays consistent while items are
+	// created and deleted. Every page except the last returns the cursor of the next one.
+	List(req ListItemsRequest) (ListItemsResponse, error)
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// NewItemStore creates an empty store with the given backend. file is the log of
+// the file backend; a temporary file removed on Close is used if it is empty.
+func NewItemStore(backend, file string) (ItemStore, error) {
+	switch backend {
+	case StoreMutex, "":
+		return newMutexStore(), nil
+	case StoreSharded:
+		return newShardedStore(), nil
+	case StoreSyncMap:
+		return newSyncMapStore(), nil
+	case StoreFile:
+		return newFileStore(file)
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", backend)
+	}
+}
+
+// StoreSnapshot summarizes the contents of a store, to check a dataset is as
+// expected before and after a run.
+type StoreSnapshot struct {
+	Items    int    `json:"items"`
+	NextID   int    `json:"next_id"`
+	Checksum string `json:"checksum"` // FNV-1a of every item in ID order
+}
+
+// StoreDump holds the full contents of a store, to verify writes after a run.
+type StoreDump struct {
+	Items  []Item `json:"items"`
+	NextID int    `json:"next_id"`
+}
+
+// snapshotOf computes the snapshot of the items in the index, looked up with get.
+// Callers hold whatever locks keep the index consistent.
+func snapshotOf(idx idIndex, nextID int, get func(id int) (Item, bool)) StoreSnapshot {
+	h := fnv.New64a()
+	snapshot := StoreSnapshot{NextID: nextID}
+	for _, item := range dumpOf(idx, nextID, get).Items {
+		fmt.Fprintf(h, "%d\x00%s\x00%s\n", item.ID, item.Name, item.Value)
+		snapshot.Items++
+	}
+	snapshot.Checksum = fmt.Sprintf("%016x", h.Sum64())
+	return snapshot
+}
+
+// dumpOf collects the items in the index, looked up with get, in ID order.
+// Callers hold whatever locks keep the index consistent.
+func dumpOf(idx idIndex, nextID int, get func(id int) (Item, bool)) StoreDump {
+	ids := idx.scan(0, idx.size(), false)
+	dump := StoreDump{Items: make([]Item, 0, len(ids)), NextID: nextID}
+	for _, id := range ids {
+		if item, ok := get(id); ok {
+			dump.Items = append(dump.Items, item)
+		}
+	}
+	return dump
+}
+
+// newItem returns the pre-populated item with the given ID.
+func newItem(id int) Item {
+	return Item{
+		ID:    id,
+		Name:  fmt.Sprintf("Item-%d", id),
+		Value: fmt.Sprintf("value-%d", id),
+	}
+}
+
+// idIndex is an ordered set of item IDs.
+type idIndex interface {
+	size() int
+	// countBelow returns the number of IDs below id.
+	countBelow(id int) int
+	// scan returns up to n IDs starting at ascending position pos, going
+	// towards lower IDs if desc.
+	scan(pos, n int, desc bool) []int
+}
+
+// sortedIDs is an idIndex over a sorted slice.
+type sortedIDs []int
+
+func (s sortedIDs) size() int {
+	return len(s)
+}
+
+func (s sortedIDs) countBelow(id int) int {
+	i, _ := slices.BinarySearch(s, id)
+	return i
+}
+
+func (s sortedIDs) scan(pos, n int, desc bool) []int {
+	if desc {
+		ids := make([]int, 0, n)
+		for i := pos; i >= 0 && len(ids) < n; i-- {
+			ids = append(ids, s[i])
+		}
+		return ids
+	}
+	return slices.Clone(s[pos:min(pos+n, len(s))])
+}
+
+// insert adds id, keeping the slice sorted. IDs usually arrive in order, so this
+// is an append.
+func (s *sortedIDs) insert(id int) {
+	if n := len(*s); n == 0 || (*s)[n-1] < id {
+		*s = append(*s, id)
+		return
+	}
+	if i, found := slices.BinarySearch(*s, id); !found {
+		*s = slices.Insert(*s, i, id)
+	}
+}
+
+// remove deletes id if it is present.
+func (s *sortedIDs) remove(id int) {
+	if i, found := slices.BinarySearch(*s, id); found {
+		*s = slices.Delete(*s, i, i+1)
+	}
+}
+
+// listPage selects the page of IDs requested from the index and looks up their
+// items with get. Callers hold whatever locks keep the index consistent.
+func listPage(idx idIndex, req ListItemsRequest, get func(id int) (Item, bool)) (ListItemsResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	order := req.Order
+	switch order {
+	case "":
+		order = OrderAsc
+	case OrderAsc, OrderDesc:
+	default:
+		return ListItemsResponse{}, fmt.Errorf("%w: %s (must be asc or desc)", ErrInvalidOrder, order)
+	}
+	desc := order == OrderDesc
+
+	total := idx.size()
+	resp := ListItemsResponse{Total: total, Limit: limit, Order: order}
+
+	// start is the position of the first item of the page in the requested order
+	var start int
+	if req.Cursor > 0 {
+		if desc {
+			start = total - idx.countBelow(req.Cursor)
+		} else {
+			start = idx.countBelow(req.Cursor + 1)
+		}
+	} else {
+		resp.Page = max(req.Page, 1)
+		start = total
+		if resp.Page-1 <= total/limit { // Avoid overflowing on huge page numbers
+			start = min((resp.Page-1)*limit, total)
+		}
+	}
+
+	resp.Items = make([]Item, 0, min(limit, total-start))
+	if start < total {
+		pos := start
+		if desc {
+			pos = total - 1 - start
+		}
+		for _, id := range idx.scan(pos, limit, desc) {
+			if item, ok := get(id); ok {
+				resp.Items = append(resp.Items, item)
+			}
+		}
+	}
+	if start+limit < total && len(resp.Items) > 0 {
+		resp.NextCursor = resp.Items[len(resp.Items)-1].ID
+	}
+	return resp, nil
+}
+
+// mutexStore keeps every item in one map behind a single lock.
+type mutexStore struct {
+	mu     sync.RWMutex
+	items  map[int]Item
+	ids    sortedIDs
+	nextID int
+}
+
+// newMutexStore creates an empty mutexStore.
+func newMutexStore() *mutexStore {
+	return &mutexStore{
+		items:  make(map[int]Item),
+		nextID: 1,
+	}
+}
+
+func (s *mutexStore) PrePopulate(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.populate(size)
+	return nil
+}
+
+// populate adds the pre-populated items. Callers hold the write lock.
+func (s *mutexStore) populate(size int) {
+	for i := 1; i <= size; i++ {
+		s.items[i] = newItem(i)
+		s.ids.insert(i)
+	}
+	s.nextID = max(s.nextID, size+1)
+}
+
+func (s *mutexStore) Reset(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items = make(map[int]Item, size)
+	s.ids = nil
+	s.nextID = 1
+	s.populate(size)
+	return nil
+}
+
+func (s *mutexStore) Snapshot() (StoreSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return snapshotOf(s.ids, s.nextID, func(id int) (Item, bool) {
+		item, ok := s.items[id]
+		return item, ok
+	}), nil
+}
+
+func (s *mutexStore) Dump() (StoreDump, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return dumpOf(s.ids, s.nextID, func(id int) (Item, bool) {
+		item, ok := s.items[id]
+		return item, ok
+	}), nil
+}
+
+func (s *mutexStore) Create(name, value string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := Item{
+		ID:    s.nextID,
+		Name:  name,
+		Value: value,
+	}
+	s.items[item.ID] = item
+	s.ids.insert(item.ID)
+	s.nextID++
+	return item, nil
+}
+
+func (s *mutexStore) Get(id int) (Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	return item, nil
+}
+
+func (s *mutexStore) Update(id int, name, value string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	item.Name = name
+	item.Value = value
+	s.items[id] = item
+	return item, nil
+}
+
+func (s *mutexStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return ErrItemNotFound
+	}
+	delete(s.items, id)
+	s.ids.remove(id)
+	return nil
+}
+
+func (s *mutexStore) List(req ListItemsRequest) (ListItemsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return listPage(s.ids, req, func(id int) (Item, bool) {
+		item, ok := s.items[id]
+		return item, ok
+	})
+}
+
+func (s *mutexStore) Close() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of the large and streamed responses.
+const (
+	DefaultLargeItems = 1000   // Items returned by /large by default
+	MaxLargeItems     = 100000 // Items returned by /large at most
+
+	DefaultStreamEvents   = 10                     // Chunks of /stream and events of /sse by default
+	MaxStreamEvents       = 10000                  // Chunks of /stream and events of /sse at most
+	DefaultStreamSize     = 1024                   // Payload bytes of each /stream chunk by default
+	MaxStreamSize         = 1 << 20                // Payload bytes of each /stream chunk at most
+	DefaultStreamInterval = 10 * time.Millisecond  // Delay between /stream chunks by default
+	DefaultSSEInterval    = 100 * time.Millisecond // Delay between /sse events by default
+	MaxStreamInterval     = 10 * time.Second       // Delay between chunks or events at most
+)
+
+// LargeRequest contains the query parameters of /large.
+type LargeRequest struct {
+	Items int `query:"items"`
+}
+
+// largeItems returns n generated items, or DefaultLargeItems if n isn't positive,
+// up to MaxLargeItems.
+func largeItems(n int) []Item {
+	if n <= 0 {
+		n = DefaultLargeItems
+	}
+	n = min(n, MaxLargeItems)
+
+	items := make([]Item, n)
+	for i := range items {
+		items[i] = Item{ID: i + 1, Name: fmt.Sprintf("item-%d", i+1), Value: fmt.Sprintf("value-%d", i+1)}
+	}
+	return items
+}
+
+// streamChunk is a line of the /stream response.
+type streamChunk struct {
+	Seq  int    `json:"seq"`
+	Data string `json:"data"`
+}
+
+// sseEvent is the data of an /sse event.
+type sseEvent struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+}
+
+// Content types of the streamed responses.
+const (
+	ndjsonContentType = "application/x-ndjson"
+	sseContentType    = "text/event-stream"
+)
+
+// StreamRequest contains the query parameters of /stream.
+type StreamRequest struct {
+	Chunks   int           // Lines streamed
+	Size     int           // Payload bytes of each line
+	Interval time.Duration // Delay between lines
+}
+
+// SSERequest contains the query parameters of /sse.
+type SSERequest struct {
+	Events   int           // Events sent
+	Interval time.Duration // Delay between events
+}
+
+// parseStreamRequest parses the query of /stream?chunks=N&size=B&interval=D,
+// query returning a parameter by name.
+func parseStreamRequest(query func(string) string) (StreamRequest, error) {
+	chunks, err := queryInt(query, "chunks", DefaultStreamEvents, MaxStreamEvents)
+	if err != nil {
+		return StreamRequest{}, err
+	}
+	size, err := queryInt(query, "size", DefaultStreamSize, MaxStreamSize)
+	if err != nil {
+		return StreamRequest{}, err
+	}
+	interval, err := queryDuration(query, "interval", DefaultStreamInterval)
+	if err != nil {
+		return StreamRequest{}, err
+	}
+	return StreamRequest{Chunks: chunks, Size: size, Interval: interval}, nil
+}
+
+// parseSSERequest parses the query of /sse?events=N&interval=D, query returning
+// a parameter by name.
+func parseSSERequest(query func(string) string) (SSERequest, error) {
+	events, err := queryInt(query, "events", DefaultStreamEvents, MaxStreamEvents)
+	if err != nil {
+		return SSERequest{}, err
+	}
+	interval, err := queryDuration(query, "interval", DefaultSSEInterval)
+	if err != nil {
+		return SSERequest{}, err
+	}
+	return SSERequest{Events: events, Interval: interval}, nil
+}
+
+// writeStream writes the /stream body: req.Chunks lines of newline-delimited
+// JSON, each carrying req.Size bytes of data, flushed req.Interval apart.
+func writeStream(ctx context.Context, w io.Writer, flush func(), req StreamRequest) {
+	data := strings.Repeat("x", req.Size)
+	enc := json.NewEncoder(w)
+	streamEvents(ctx, flush, req.Chunks, req.Interval, func(seq int) error {
+		return enc.Encode(streamChunk{Seq: seq, Data: data})
+	})
+}
+
+// writeSSE writes the /sse body: req.Events server-sent events, req.Interval apart.
+func writeSSE(ctx context.Context, w io.Writer, flush func(), req SSERequest) {
+	streamEvents(ctx, flush, req.Events, req.Interval, func(seq int) error {
+		data, err := json.Marshal(sseEvent{Seq: seq, Time: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: tick\ndata: %s\n\n", seq, data)
+		return err
+	})
+}
+
+// streamEvents writes n events with write, flushing each one and waiting interval
+// between them. It stops early when ctx is done, as when the client goes away, or
+// a write fails.
+func streamEvents(ctx context.Context, flush func(), n int, interval time.Duration, write func(seq int) error) {
+	var timer *time.Timer
+	for seq := 1; seq <= n; seq++ {
+		if seq > 1 && interval > 0 {
+			if timer == nil {
+				timer = time.NewTimer(interval)
+				defer timer.Stop()
+			} else {
+				timer.Reset(interval)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		}
+		if err := write(seq); err != nil {
+			return
+		}
+		flush()
+	}
+}
+
+// queryInt parses a positive integer query parameter, returning def if it is
+// missing and at most max.
+func queryInt(query func(string) string, name string, def, max int) (int, error) {
+	s := query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return min(n, max), nil
+}
+
+// queryDuration parses a duration query parameter, returning def if it is missing
+// and at most MaxStreamInterval.
+func queryDuration(query func(string) string, name string, def time.Duration) (time.Duration, error) {
+	s := query(name)
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return min(d, MaxStreamInterval), nil
+}
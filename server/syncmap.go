@@ -0,0 +1,139 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// syncMapStore keeps items in a sync.Map, so reads and updates don't take a lock.
+// Creates, deletes, resets and listing share a lock around the ordered index; IDs
+// are taken under it, so a create can't race a reset.
+type syncMapStore struct {
+	items  sync.Map // int -> Item
+	mu     sync.RWMutex
+	ids    sortedIDs
+	nextID atomic.Int64
+}
+
+// newSyncMapStore creates an empty syncMapStore.
+func newSyncMapStore() *syncMapStore {
+	return &syncMapStore{}
+}
+
+func (s *syncMapStore) PrePopulate(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.populate(size)
+	return nil
+}
+
+// populate adds the pre-populated items. Callers hold the index lock.
+func (s *syncMapStore) populate(size int) {
+	for i := 1; i <= size; i++ {
+		s.items.Store(i, newItem(i))
+		s.ids.insert(i)
+	}
+	for {
+		next := s.nextID.Load()
+		if next >= int64(size) || s.nextID.CompareAndSwap(next, int64(size)) {
+			return
+		}
+	}
+}
+
+// Reset doesn't block reads and updates, which may briefly see the old items.
+func (s *syncMapStore) Reset(size int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items.Clear()
+	s.ids = nil
+	s.nextID.Store(0)
+	s.populate(size)
+	return nil
+}
+
+func (s *syncMapStore) Snapshot() (StoreSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return snapshotOf(s.ids, int(s.nextID.Load())+1, s.load), nil
+}
+
+func (s *syncMapStore) Dump() (StoreDump, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return dumpOf(s.ids, int(s.nextID.Load())+1, s.load), nil
+}
+
+// load returns the item with the given ID.
+func (s *syncMapStore) load(id int) (Item, bool) {
+	v, ok := s.items.Load(id)
+	if !ok {
+		return Item{}, false
+	}
+	return v.(Item), true
+}
+
+func (s *syncMapStore) Create(name, value string) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := Item{
+		ID:    int(s.nextID.Add(1)),
+		Name:  name,
+		Value: value,
+	}
+	s.items.Store(item.ID, item)
+	s.ids.insert(item.ID)
+	return item, nil
+}
+
+func (s *syncMapStore) Get(id int) (Item, error) {
+	v, ok := s.items.Load(id)
+	if !ok {
+		return Item{}, ErrItemNotFound
+	}
+	return v.(Item), nil
+}
+
+// Update swaps in the new item only if the stored one is unchanged, retrying
+// otherwise, so it never resurrects an item deleted concurrently.
+func (s *syncMapStore) Update(id int, name, value string) (Item, error) {
+	for {
+		v, ok := s.items.Load(id)
+		if !ok {
+			return Item{}, ErrItemNotFound
+		}
+		item := v.(Item)
+		item.Name = name
+		item.Value = value
+		if s.items.CompareAndSwap(id, v, item) {
+			return item, nil
+		}
+	}
+}
+
+func (s *syncMapStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items.LoadAndDelete(id); !ok {
+		return ErrItemNotFound
+	}
+	s.ids.remove(id)
+	return nil
+}
+
+func (s *syncMapStore) List(req ListItemsRequest) (ListItemsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return listPage(s.ids, req, s.load)
+}
+
+func (s *syncMapStore) Close() error {
+	return nil
+}
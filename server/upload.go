@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+)
+
+// Limits of the upload routes.
+const (
+	MaxUploadSize      = 1 << 30  // Request body bytes accepted at most
+	MultipartMaxMemory = 32 << 20 // Multipart file bytes kept in memory, beyond which they spill to temporary files
+)
+
+// UploadResponse describes a parsed upload.
+type UploadResponse struct {
+	Fields int   `json:"fields"`          // Form fields
+	Files  int   `json:"files,omitempty"` // Multipart files
+	Bytes  int64 `json:"bytes"`           // Bytes of field values, files or raw body
+}
+
+// FormUploadRequest is the urlencoded form bound by the Helix /upload/form route:
+// the fields sent by the load generator.
+type FormUploadRequest struct {
+	Name  string `form:"name"`
+	Value string `form:"value"`
+}
+
+// response describes the bound form. Empty fields aren't counted.
+func (req FormUploadRequest) response() UploadResponse {
+	var resp UploadResponse
+	for _, v := range []string{req.Name, req.Value} {
+		if v != "" {
+			resp.Fields++
+			resp.Bytes += int64(len(v))
+		}
+	}
+	return resp
+}
+
+// MultipartUploadRequest is the multipart form bound by the Helix
+// /upload/multipart route: the fields and file sent by the load generator.
+type MultipartUploadRequest struct {
+	Name  string                `form:"name"`
+	Value string                `form:"value"`
+	File  *multipart.FileHeader `form:"file"`
+}
+
+// response describes the bound form. Empty fields aren't counted.
+func (req MultipartUploadRequest) response() UploadResponse {
+	resp := FormUploadRequest{Name: req.Name, Value: req.Value}.response()
+	if req.File != nil {
+		resp.Files++
+		resp.Bytes += req.File.Size
+	}
+	return resp
+}
+
+// limitBody returns middleware rejecting request bodies over max bytes: at once
+// when the request declares its length, or else when reading past max.
+func limitBody(max int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > max {
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body exceeds %d bytes", max))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+			next.ServeHTTP(w, r)
+		})
+	}
+}